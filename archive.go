@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+// ========================= Arsip lokal =========================
+
+const archiveFileName = "archive.db"
+
+// archivePath: bawaan archive.db di dataDir, terpisah dari file akun.
+func archivePath() string {
+	if cfg.ArchivePath != "" {
+		return cfg.ArchivePath
+	}
+	if d := dataDir(); d != "" {
+		return filepath.Join(d, archiveFileName)
+	}
+	return archiveFileName
+}
+
+// Archive menyimpan salinan pesan per akun agar tetap bisa dibaca setelah
+// akun kedaluwarsa atau dihapus. Key di kvStore:
+//
+//	acct/<alamat>                  archiveAccount
+//	msg/<alamat>/<id>              detail pesan (JSON mailtm.Message)
+//	src/<alamat>/<id>              sumber mentah RFC 5322
+//	att/<alamat>/<id>/<lampiran>   isi lampiran
+//
+// Pesan yang sudah diarsipkan tidak diambil ulang, jadi status dibaca di
+// arsip adalah status saat pesan pertama kali disalin.
+type Archive struct {
+	kv *kvStore
+}
+
+type archiveAccount struct {
+	Address   string    `json:"address"`
+	AccountID string    `json:"account_id"`
+	Nickname  string    `json:"nickname,omitempty"`
+	Key       string    `json:"key,omitempty"` // key akun di penyimpanan saat sinkronisasi
+	Tags      []string  `json:"tags,omitempty"`
+	LastSync  time.Time `json:"last_sync"`
+	// Complete berarti sinkronisasi terakhir sampai ke pesan paling lama,
+	// sehingga sinkronisasi berikutnya boleh berhenti di pesan pertama yang
+	// sudah ada.
+	Complete bool `json:"complete"`
+}
+
+// openArchive membuka arsip. Untuk ditulis, lock-nya dipegang sampai Close
+// sehingga proses lain yang ingin menulis menunggu; readOnly tidak mengunci
+// dan tidak pernah mengubah file, juga bila file rusak.
+func openArchive(readOnly bool) (*Archive, error) {
+	open := openKV
+	if readOnly {
+		open = openKVReadOnly
+	}
+	kv, err := open(archivePath())
+	if err != nil {
+		return nil, err
+	}
+	if kv.Damaged > 0 {
+		fmt.Fprintf(os.Stderr, "peringatan: %d byte rusak di %s dilewati\n", kv.Damaged, kv.path)
+	}
+	if kv.Backup != "" {
+		fmt.Fprintf(os.Stderr, "peringatan: arsip diperbaiki, salinan sebelum diperbaiki: %s\n", kv.Backup)
+	}
+	return &Archive{kv: kv}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.kv.Close()
+}
+
+func archiveKey(kind, address string, parts ...string) string {
+	return strings.Join(append([]string{kind, strings.ToLower(address)}, parts...), "/")
+}
+
+func (a *Archive) getJSON(key string, v any) (bool, error) {
+	b, ok, err := a.kv.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("archive %s: %w", key, err)
+	}
+	return true, nil
+}
+
+func (a *Archive) putJSON(key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return a.kv.Put(key, b)
+}
+
+type archiveOptions struct {
+	Sources     bool // simpan juga sumber mentah
+	Attachments bool // simpan juga isi lampiran
+}
+
+type archiveSyncResult struct {
+	Address     string
+	New         int // detail pesan yang baru disalin
+	Sources     int
+	Attachments int
+	Total       int // pesan akun ini di arsip setelah sinkronisasi
+}
+
+// Sync menyalin pesan client yang belum ada di arsip. Yang sudah tersalin
+// tetap tersimpan meski sinkronisasi gagal di tengah jalan, sehingga
+// pemanggilan berikutnya melanjutkan dari sana.
+func (a *Archive) Sync(ctx context.Context, client *Client, opt archiveOptions) (archiveSyncResult, error) {
+	res := archiveSyncResult{Address: client.Address}
+	var acct archiveAccount
+	if _, err := a.getJSON(archiveKey("acct", client.Address), &acct); err != nil {
+		return res, err
+	}
+	acct.Address, acct.AccountID, acct.Key = client.Address, client.AccountID, client.AccountKey
+	if stored, ok := client.Store.Get(client.AccountKey); ok {
+		acct.Nickname, acct.Tags = stored.Nickname, stored.Tags
+	}
+	// sumber/lampiran yang baru diminta perlu mengisi pesan lama juga
+	stopAtKnown := acct.Complete && !opt.Sources && !opt.Attachments
+	// bila gagal di tengah, celah di antara pesan lama dan baru harus diisi
+	// dengan menelusuri semua pesan lagi
+	acct.Complete = false
+
+	err := func() error {
+		for m, err := range client.AllMessages(ctx) {
+			if err != nil {
+				return err
+			}
+			key := archiveKey("msg", client.Address, m.ID)
+			var det mailtm.Message
+			known, err := a.getJSON(key, &det)
+			if err != nil {
+				return err
+			}
+			if known && stopAtKnown {
+				break
+			}
+			if !known {
+				d, err := client.GetMessage(ctx, m.ID)
+				if errors.Is(err, mailtm.ErrNotFound) {
+					continue // dihapus sejak daftar diambil
+				}
+				if err != nil {
+					return fmt.Errorf("message %s: %w", m.ID, err)
+				}
+				if err := a.putJSON(key, d); err != nil {
+					return err
+				}
+				det = *d
+				res.New++
+			}
+			if opt.Sources {
+				n, err := a.syncSource(ctx, client, m.ID)
+				if err != nil {
+					return fmt.Errorf("source %s: %w", m.ID, err)
+				}
+				res.Sources += n
+			}
+			if opt.Attachments {
+				n, err := a.syncAttachments(ctx, client, &det)
+				if err != nil {
+					return fmt.Errorf("message %s: %w", m.ID, err)
+				}
+				res.Attachments += n
+			}
+		}
+		acct.Complete = true
+		return nil
+	}()
+	if err != nil && res.New == 0 && res.Sources == 0 && res.Attachments == 0 {
+		return res, err
+	}
+	acct.LastSync = time.Now()
+	if perr := a.putJSON(archiveKey("acct", client.Address), acct); err == nil {
+		err = perr
+	}
+	if serr := a.kv.Sync(); err == nil {
+		err = serr
+	}
+	res.Total = len(a.kv.Keys(archiveKey("msg", client.Address) + "/"))
+	return res, err
+}
+
+func (a *Archive) syncSource(ctx context.Context, client *Client, id string) (int, error) {
+	key := archiveKey("src", client.Address, id)
+	if a.kv.Has(key) {
+		return 0, nil
+	}
+	src, err := client.GetSource(ctx, id)
+	if errors.Is(err, mailtm.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, a.kv.Put(key, []byte(src.Data))
+}
+
+func (a *Archive) syncAttachments(ctx context.Context, client *Client, m *mailtm.Message) (int, error) {
+	n := 0
+	for _, att := range m.Attachments {
+		key := archiveKey("att", client.Address, m.ID, att.ID)
+		if a.kv.Has(key) {
+			continue
+		}
+		body, err := client.DownloadAttachment(ctx, att)
+		if errors.Is(err, mailtm.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return n, fmt.Errorf("attachment %s: %w", nz(att.Filename, att.ID), err)
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return n, fmt.Errorf("attachment %s: %w", nz(att.Filename, att.ID), err)
+		}
+		if err := a.kv.Put(key, data); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Accounts mengembalikan akun yang punya arsip, urut alamat, beserta jumlah
+// pesannya.
+func (a *Archive) Accounts() ([]archiveAccount, map[string]int, error) {
+	var out []archiveAccount
+	counts := map[string]int{}
+	for _, k := range a.kv.Keys("acct/") {
+		var acct archiveAccount
+		if _, err := a.getJSON(k, &acct); err != nil {
+			return nil, nil, err
+		}
+		out = append(out, acct)
+		counts[acct.Address] = len(a.kv.Keys(archiveKey("msg", acct.Address) + "/"))
+	}
+	return out, counts, nil
+}
+
+// HasAccount melaporkan apakah alamat punya arsip.
+func (a *Archive) HasAccount(address string) bool {
+	return a.kv.Has(archiveKey("acct", address))
+}
+
+// HasMessage melaporkan apakah detail pesan sudah diarsipkan.
+func (a *Archive) HasMessage(address, id string) bool {
+	return a.kv.Has(archiveKey("msg", address, id))
+}
+
+// Messages mengembalikan semua pesan arsip satu akun, terbaru dulu.
+func (a *Archive) Messages(address string) ([]mailtm.Message, error) {
+	keys := a.kv.Keys(archiveKey("msg", address) + "/")
+	out := make([]mailtm.Message, 0, len(keys))
+	for _, k := range keys {
+		var m mailtm.Message
+		if _, err := a.getJSON(k, &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	// ID Mail.tm berurutan waktu, jadi dipakai bila detiknya sama
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt > out[j].CreatedAt
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// Message mencari pesan arsip berdasarkan ID. Bila address kosong, semua
+// akun dicari; ID yang sama di dua akun dianggap ambigu.
+func (a *Archive) Message(address, id string) (*mailtm.Message, string, error) {
+	var found []string
+	if address != "" {
+		if k := archiveKey("msg", address, id); a.kv.Has(k) {
+			found = append(found, k)
+		}
+	} else {
+		for _, k := range a.kv.Keys("msg/") {
+			if strings.HasSuffix(k, "/"+id) {
+				found = append(found, k)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, "", fmt.Errorf("message not found in archive: %s", id)
+	case 1:
+	default:
+		return nil, "", fmt.Errorf("message %s is archived for more than one account; use --account", id)
+	}
+	var m mailtm.Message
+	if _, err := a.getJSON(found[0], &m); err != nil {
+		return nil, "", err
+	}
+	addr := strings.TrimSuffix(strings.TrimPrefix(found[0], "msg/"), "/"+id)
+	return &m, addr, nil
+}
+
+// Source mengembalikan sumber mentah yang diarsipkan; ok false bila tidak
+// ikut disalin.
+func (a *Archive) Source(address, id string) ([]byte, bool, error) {
+	return a.kv.Get(archiveKey("src", address, id))
+}
+
+// Attachment mengembalikan isi lampiran yang diarsipkan.
+func (a *Archive) Attachment(address, id, attID string) ([]byte, bool, error) {
+	return a.kv.Get(archiveKey("att", address, id, attID))
+}
+
+// archiveBeforeDelete menyalin semua pesan akun, termasuk sumber dan
+// lampirannya, karena setelah akun dihapus isinya tidak bisa diambil lagi.
+func archiveBeforeDelete(ctx context.Context, client *Client) (archiveSyncResult, error) {
+	ar, err := openArchive(false)
+	if err != nil {
+		return archiveSyncResult{}, err
+	}
+	res, err := ar.Sync(ctx, client, archiveOptions{Sources: true, Attachments: true})
+	if cerr := ar.Close(); err == nil {
+		err = cerr
+	}
+	return res, err
+}
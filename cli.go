@@ -0,0 +1,1697 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+	"github.com/luzyver/Mail.TM-CLI/mailtm/mailtmtest"
+)
+
+// ========================= CLI (non-interaktif) =========================
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"create", "[--domain D|random] [--username U | --username-style S] [--password P] [--nickname N]", "Buat akun email baru dan simpan", cmdCreate},
+		{"domains", "[--all]", "Tampilkan domain yang tersedia", cmdDomains},
+		{"accounts", "[--stats]", "Tampilkan akun tersimpan, opsional dengan ringkasan kotak masuk", cmdAccounts},
+		{"inbox", "[--account KEY] [--page N | --all] [--unread]", "Tampilkan pesan masuk", cmdInbox},
+		{"read", "[--account KEY] [--html] <id>", "Tampilkan detail pesan", cmdRead},
+		{"wait", "[--account KEY] [--timeout 30s] [--count N] [--from A] [--subject RE] [--contains T] [--since 5m]", "Tunggu pesan baru yang cocok (exit 3 jika timeout)", cmdWait},
+		{"otp", "[--account KEY] [--timeout 30s] [--from A] [--since 5m] [--all]", "Tunggu pesan berikutnya dan cetak kode verifikasinya (exit 3 jika timeout)", cmdOTP},
+		{"mark-read", "[--account KEY] (--all | ID...)", "Tandai pesan sudah dibaca", cmdMarkSeen(true)},
+		{"mark-unread", "[--account KEY] (--all | ID...)", "Tandai pesan belum dibaca", cmdMarkSeen(false)},
+		{"attachments", "[--account KEY] [--save] [--dir DIR] [--force] ID [LAMPIRAN...]", "Daftar atau simpan lampiran pesan", cmdAttachments},
+		{"source", "[--account KEY] [--save] [--dir DIR] ID", "Cetak atau simpan sumber mentah pesan (.eml)", cmdSource},
+		{"mime", "[--account KEY] [--file F.eml] [--part N] [ID]", "Tampilkan header dan struktur MIME pesan, atau isi satu bagian", cmdMIME},
+		{"links", "[--account KEY] [--host H] [--match RE] [--first] [--wait] [ID]", "Cetak tautan di pesan (default: pesan terbaru)", cmdLinks},
+		{"watch", "[--account KEY | --all | --tag T1,T2] [--parallel 4] [--interval 5s] [--poll]", "Pantau pesan baru terus-menerus, satu atau banyak akun (Ctrl+C untuk berhenti)", cmdWatch},
+		{"archive", "sync [--account KEY | --all | --tag T1,T2] [--sources] [--attachments] | list [--account A] | show [--account A] [--html] [--source] [--save DIR] ID", "Salin pesan ke arsip lokal dan baca lagi secara offline", cmdArchive},
+		{"search", "[--offline | --sync] [--limit 20] QUERY...", "Cari pesan di semua akun dan arsip (from:, subject:, account:, before:, after:); --sync menyimpan pesan ke arsip", cmdSearch},
+		{"delete-message", "[--account KEY] [--all] <id>...", "Hapus pesan", cmdDeleteMessage},
+		{"delete-account", "[--account KEY] [--local] [--archive]", "Hapus akun dari server dan penyimpanan", cmdDeleteAccount},
+		{"rename", "[--account KEY] <nickname>", "Ubah nickname akun", cmdRename},
+		{"tag", "[--account KEY] [--remove] [TAG...]", "Tambah, hapus atau tampilkan tag akun", cmdTag},
+		{"encrypt", "", "Enkripsi penyimpanan akun dengan passphrase", cmdEncrypt},
+		{"decrypt", "", "Simpan ulang penyimpanan akun tanpa enkripsi", cmdDecrypt},
+		{"rekey", "", "Ganti passphrase penyimpanan terenkripsi", cmdRekey},
+		{"config", "", "Tampilkan konfigurasi yang berlaku", cmdConfig},
+		{"recover", "[--from FILE] [--dry-run]", "Pulihkan akun dari file rusak atau backup", cmdRecover},
+		{"fake-server", "[--listen ADDR] [--domains a.test,b.test]", "Jalankan server Mail.tm palsu untuk uji coba dan demo", cmdFakeServer},
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Penggunaan: mailtm [opsi global] [perintah] [opsi]")
+	fmt.Fprintln(os.Stderr, "\nTanpa perintah, menu interaktif akan dibuka.")
+	fmt.Fprintln(os.Stderr, "\nPerintah:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.short)
+	}
+	fmt.Fprintln(os.Stderr, "\nOpsi global:")
+	fs := flag.NewFlagSet("mailtm", flag.ContinueOnError)
+	addGlobalFlags(fs)
+	fs.SetOutput(os.Stderr)
+	fs.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\nGunakan 'mailtm <perintah> -h' untuk opsi tiap perintah.")
+}
+
+// addGlobalFlags mendaftarkan opsi yang berlaku untuk semua perintah, baik
+// ditulis sebelum maupun sesudah nama perintah.
+func addGlobalFlags(fs *flag.FlagSet) {
+	fs.Var(&outFmt, "output", "format keluaran: plain, table, json, ndjson")
+	fs.Var(&outFmt, "o", "singkatan untuk --output")
+	addConfigFlags(fs)
+}
+
+// exitTimeout dipakai perintah yang menunggu saat batas waktu habis, agar
+// skrip bisa membedakannya dari error lain (exit 1).
+const exitTimeout = 3
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func runCLI(args []string) int {
+	gfs := flag.NewFlagSet("mailtm", flag.ContinueOnError)
+	gfs.SetOutput(io.Discard)
+	addGlobalFlags(gfs)
+	if err := gfs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage()
+			return 0
+		}
+		reportError("", err)
+		return 2
+	}
+	args = gfs.Args()
+	if len(args) == 0 {
+		mainMenu()
+		return 0
+	}
+	name := args[0]
+	if name == "help" {
+		usage()
+		return 0
+	}
+	// Ctrl+C membatalkan request yang sedang berjalan; Ctrl+C kedua
+	// menghentikan proses seperti biasa.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(ctx, args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			reportError(name, err)
+			var ee *exitError
+			if errors.As(err, &ee) {
+				return ee.code
+			}
+			return 1
+		}
+		return 0
+	}
+	reportError("", fmt.Errorf("unknown command: %s", name))
+	if !outFmt.machine() {
+		fmt.Fprintln(os.Stderr)
+		usage()
+	}
+	return 2
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	addGlobalFlags(fs)
+	fs.Usage = func() {
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(fs.Output(), "Penggunaan: mailtm %s %s\n\n%s\n\n", c.name, c.args, c.short)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags mengizinkan flag diletakkan sebelum maupun sesudah argumen posisi.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	fs.SetOutput(io.Discard)
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				fs.SetOutput(os.Stderr)
+				fs.Usage()
+			}
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// isSet melaporkan apakah flag name diberikan secara eksplisit.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// resolveAccount menerima key penyimpanan atau alamat email. Jika kosong dan
+// hanya ada satu akun tersimpan, akun itu yang dipakai.
+func resolveAccount(store *Storage, ref string) (string, error) {
+	accts := store.All()
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		if len(accts) == 0 {
+			return "", errors.New("no accounts stored")
+		}
+		if len(accts) == 1 {
+			for k := range accts {
+				return k, nil
+			}
+		}
+		return "", errors.New("--account is required when more than one account is stored")
+	}
+	if _, ok := accts[ref]; ok {
+		return ref, nil
+	}
+	for k, a := range accts {
+		if strings.EqualFold(a.Address, ref) {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("account key not found: %s", ref)
+}
+
+// loadStore memuat penyimpanan dan gagal jika file ada tapi tidak bisa
+// dibaca (rusak atau passphrase salah).
+func loadStore() (*Storage, error) {
+	store := NewStorage(cfg.StorePath)
+	if err := store.Err(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func openAccount(ctx context.Context, ref string) (*Client, error) {
+	client := NewClient("", cfg.StorePath)
+	if err := client.Store.Err(); err != nil {
+		return nil, err
+	}
+	key, err := resolveAccount(client.Store, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.LoadAccount(ctx, key); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func cmdCreate(ctx context.Context, args []string) error {
+	fs := newFlagSet("create")
+	username := fs.String("username", "", "username kustom (kosong = acak)")
+	style := fs.String("username-style", mailtm.UsernameRandom, "gaya username acak: "+strings.Join(mailtm.UsernameStyles, ", "))
+	userLen := fs.Int("username-length", 10, "panjang username acak (random/pronounceable)")
+	userCharset := fs.String("username-charset", "", "karakter username acak gaya random (kosong = huruf kecil & angka)")
+	password := fs.String("password", "", "password (kosong = acak)")
+	passLen := fs.Int("password-length", mailtm.DefaultPasswordPolicy.Length, "panjang password acak")
+	passClasses := fs.Int("password-classes", mailtm.DefaultPasswordPolicy.MinClasses, "minimal kelas karakter password (1-4: kecil, besar, angka, simbol)")
+	allowAmbig := fs.Bool("allow-ambiguous", false, "izinkan karakter mirip (0/O, 1/l/I) di password")
+	nickname := fs.String("nickname", "", "nickname akun")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	user := strings.TrimSpace(*username)
+	if user == "" {
+		var err error
+		opt := mailtm.UsernameOptions{Style: *style, Length: *userLen, Charset: *userCharset}
+		if user, err = opt.Generate(); err != nil {
+			return err
+		}
+	}
+	pass := *password
+	if pass == "" {
+		policy := mailtm.PasswordPolicy{Length: *passLen, MinClasses: *passClasses, ExcludeAmbiguous: !*allowAmbig}
+		var err error
+		if pass, err = policy.Generate(); err != nil {
+			return err
+		}
+	}
+	client := NewClient("", cfg.StorePath)
+	if err := client.Create(ctx, user, pass, strings.TrimSpace(*nickname), true); err != nil {
+		return err
+	}
+	acc, _ := client.Store.Get(client.AccountKey)
+	emit("account", toAccountOut(client.AccountKey, acc, true), func(w io.Writer) {
+		fmt.Fprintf(w, "Key: %s\nEmail: %s\nPassword: %s\nNickname: %s\n",
+			client.AccountKey, client.Address, client.Password, nz(*nickname, "Tanpa nama"))
+	})
+	return nil
+}
+
+func cmdDomains(ctx context.Context, args []string) error {
+	fs := newFlagSet("domains")
+	all := fs.Bool("all", false, "termasuk domain yang tidak aktif")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	doms, err := NewClient("", cfg.StorePath).GetDomains(ctx)
+	if err != nil {
+		return err
+	}
+	items := make([]domainOut, 0, len(doms))
+	for _, d := range doms {
+		if !d.IsActive && !*all {
+			continue
+		}
+		items = append(items, domainOut{ID: d.ID, Domain: d.Domain, Active: d.IsActive, Private: d.IsPrivate, CreatedAt: d.CreatedAt})
+	}
+	yn := func(b bool) string {
+		if b {
+			return "ya"
+		}
+		return "tidak"
+	}
+	emitList("domain", items, []string{"DOMAIN", "AKTIF", "PRIVAT", "DIBUAT"}, func(d domainOut) []string {
+		return []string{d.Domain, yn(d.Active), yn(d.Private), nz(d.CreatedAt, "Unknown")}
+	})
+	return nil
+}
+
+func cmdAccounts(ctx context.Context, args []string) error {
+	fs := newFlagSet("accounts")
+	withStats := fs.Bool("stats", false, "ambil jumlah pesan, belum dibaca dan pesan terakhir tiap akun")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	accts := store.All()
+	keys := make([]string, 0, len(accts))
+	for k := range accts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]accountOut, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, toAccountOut(k, accts[k], false))
+	}
+	if !*withStats {
+		emitList("account", items, []string{"KEY", "EMAIL", "NICKNAME", "TAG"}, func(a accountOut) []string {
+			return []string{a.Key, a.Address, nz(a.Nickname, "Tanpa nama"), strings.Join(a.Tags, ",")}
+		})
+		return nil
+	}
+
+	stats := dashboardCache.get(ctx, store, keys)
+	for i := range items {
+		items[i].Stats = toStatsOut(stats[items[i].Key])
+	}
+	emitList("account", items, []string{"KEY", "EMAIL", "PESAN", "BARU", "TERAKHIR", "SUBJEK TERAKHIR"}, func(a accountOut) []string {
+		st := stats[a.Key]
+		if st.Err != nil {
+			return []string{a.Key, a.Address, "-", "-", "-", "error: " + st.Err.Error()}
+		}
+		last := ago(st.LastAt)
+		if outFmt == outPlain && !st.LastAt.IsZero() {
+			last = st.LastAt.Format(time.RFC3339)
+		}
+		return []string{a.Key, a.Address, strconv.Itoa(st.Total), st.unreadLabel(), last, nz(st.LastSubject, "-")}
+	})
+	return nil
+}
+
+func cmdInbox(ctx context.Context, args []string) error {
+	fs := newFlagSet("inbox")
+	account := fs.String("account", "", "key atau alamat akun")
+	page := fs.Int("page", 1, "nomor halaman")
+	all := fs.Bool("all", false, "ambil semua halaman")
+	unread := fs.Bool("unread", false, "hanya pesan yang belum dibaca")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	var msgs []mailtm.Message
+	if *all {
+		for m, err := range client.AllMessages(ctx) {
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, m)
+		}
+	} else {
+		p, err := client.GetMessagesPage(ctx, *page)
+		if err != nil {
+			return err
+		}
+		msgs = p.Messages
+		if !outFmt.machine() && p.Last > 1 {
+			fmt.Fprintf(os.Stderr, "Halaman %d/%d (%d pesan)\n", p.Page, p.Last, p.TotalItems)
+		}
+	}
+	items := make([]messageOut, 0, len(msgs))
+	unseen := 0
+	for i := range msgs {
+		if !msgs[i].Seen {
+			unseen++
+		} else if *unread {
+			continue
+		}
+		items = append(items, toMessageOut(&msgs[i], false))
+	}
+	if !outFmt.machine() && unseen > 0 {
+		fmt.Fprintf(os.Stderr, "%d pesan belum dibaca\n", unseen)
+	}
+	// table memberi tanda * di depan; plain menambah kolom status di akhir
+	// agar urutan kolom lama tidak bergeser
+	header := []string{"", "ID", "TANGGAL", "DARI", "SUBJEK"}
+	emitList("message", items, header, func(m messageOut) []string {
+		row := []string{m.ID, nz(m.CreatedAt, "Unknown"), nz(m.From, "Unknown"), nz(m.Subject, "No Subject")}
+		if outFmt == outPlain {
+			return append(row, seenLabel(m.Seen))
+		}
+		mark := ""
+		if !m.Seen {
+			mark = "*"
+		}
+		return append([]string{mark}, row...)
+	})
+	return nil
+}
+
+func seenLabel(seen bool) string {
+	if seen {
+		return "seen"
+	}
+	return "unread"
+}
+
+// cmdMarkSeen membuat perintah mark-read (seen=true) atau mark-unread.
+func cmdMarkSeen(seen bool) func(context.Context, []string) error {
+	name := "mark-unread"
+	if seen {
+		name = "mark-read"
+	}
+	return func(ctx context.Context, args []string) error {
+		fs := newFlagSet(name)
+		account := fs.String("account", "", "key atau alamat akun")
+		all := fs.Bool("all", false, "semua pesan di kotak masuk")
+		ids, err := parseFlags(fs, args)
+		if err != nil {
+			return err
+		}
+		if *all == (len(ids) > 0) {
+			return errors.New("either --all or at least one message id is required")
+		}
+		client, err := openAccount(ctx, *account)
+		if err != nil {
+			return err
+		}
+		cnt := 0
+		if *all {
+			cnt, err = client.MarkAllSeen(ctx, seen)
+		} else {
+			var errs []error
+			for _, id := range ids {
+				if err := client.MarkSeen(ctx, id, seen); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", id, err))
+					continue
+				}
+				cnt++
+			}
+			err = errors.Join(errs...)
+		}
+		state := "belum dibaca"
+		if seen {
+			state = "sudah dibaca"
+		}
+		if cnt > 0 || err == nil {
+			emitResult(resultOut{Action: name, Address: client.Address, Count: cnt, Detail: seenLabel(seen)},
+				fmt.Sprintf("%d pesan ditandai %s.", cnt, state))
+		}
+		return err
+	}
+}
+
+func printMessage(m *mailtm.Message, html bool) {
+	emit("message", toMessageOut(m, true), func(w io.Writer) {
+		fmt.Fprintln(w, "ID:", m.ID)
+		fmt.Fprintln(w, "Dari:", nz(m.From.Address, "Unknown"))
+		fmt.Fprintln(w, "Subjek:", nz(m.Subject, "No Subject"))
+		fmt.Fprintln(w, "Tanggal:", nz(m.CreatedAt, "Unknown"))
+		printCode(w, m)
+		printAttachments(w, m)
+		htmlStr := m.HTML.String()
+		if html && htmlStr != "" {
+			fmt.Fprintln(w, "\n"+htmlStr)
+			return
+		}
+		fmt.Fprintln(w, "\n"+nz(m.Text, nz(htmlStr, "No Content")))
+	})
+}
+
+func cmdRead(ctx context.Context, args []string) error {
+	fs := newFlagSet("read")
+	account := fs.String("account", "", "key atau alamat akun")
+	html := fs.Bool("html", false, "tampilkan isi HTML")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("exactly one message id is required")
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	det, err := client.GetMessage(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	printMessage(det, *html)
+	return nil
+}
+
+func cmdWait(ctx context.Context, args []string) error {
+	fs := newFlagSet("wait")
+	account := fs.String("account", "", "key atau alamat akun")
+	timeout := fs.Duration("timeout", time.Duration(cfg.WaitTimeout), "batas waktu menunggu")
+	interval := fs.Duration("interval", time.Duration(cfg.PollInterval), "jeda antar pengecekan")
+	html := fs.Bool("html", false, "tampilkan isi HTML")
+	poll := fs.Bool("poll", false, "jangan pakai hub Mercure (SSE), selalu polling")
+	count := fs.Int("count", 1, "jumlah pesan yang ditunggu")
+	filter := addWaitFilterFlags(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	wf, err := filter()
+	if err != nil {
+		return err
+	}
+	// --wait-timeout/--poll-interval global berlaku jika opsi lokal tidak diisi
+	if !isSet(fs, "timeout") {
+		*timeout = time.Duration(cfg.WaitTimeout)
+	}
+	if !isSet(fs, "interval") {
+		*interval = time.Duration(cfg.PollInterval)
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	if *poll {
+		client.MercureURL = ""
+	}
+	msgs, err := client.WaitForN(ctx, *count, *timeout, *interval, wf)
+	// pesan yang sempat diterima tetap dicetak walau timeout
+	if *count > 1 && outFmt == outJSON {
+		items := make([]messageOut, len(msgs))
+		for i := range msgs {
+			items[i] = toMessageOut(&msgs[i], true)
+		}
+		emitList("message", items, nil, nil)
+	} else {
+		for i := range msgs {
+			if i > 0 && !outFmt.machine() {
+				fmt.Println("\n" + strings.Repeat("-", 50))
+			}
+			printMessage(&msgs[i], *html)
+		}
+	}
+	return asTimeout(err)
+}
+
+// addWaitFilterFlags mendaftarkan opsi penyaring untuk perintah yang
+// menunggu pesan; fungsi yang dikembalikan dipanggil setelah parseFlags.
+func addWaitFilterFlags(fs *flag.FlagSet) func() (mailtm.WaitFilter, error) {
+	from := fs.String("from", "", "hanya pesan dari alamat yang mengandung teks ini")
+	subject := fs.String("subject", "", "hanya pesan yang subjeknya cocok dengan regex ini")
+	contains := fs.String("contains", "", "hanya pesan yang isinya mengandung teks ini")
+	since := fs.String("since", "", "ikut periksa pesan yang sudah ada sejak waktu ini (durasi mis. 5m, atau RFC 3339)")
+	return func() (mailtm.WaitFilter, error) {
+		wf := mailtm.WaitFilter{From: *from, Body: *contains}
+		if *subject != "" {
+			re, err := regexp.Compile(*subject)
+			if err != nil {
+				return wf, fmt.Errorf("invalid --subject: %w", err)
+			}
+			wf.Subject = re
+		}
+		if *since != "" {
+			if d, err := time.ParseDuration(*since); err == nil {
+				wf.After = time.Now().Add(-d)
+			} else if t, err := time.Parse(time.RFC3339, *since); err == nil {
+				wf.After = t
+			} else {
+				return wf, fmt.Errorf("invalid --since %q: want a duration or RFC 3339 time", *since)
+			}
+		}
+		return wf, nil
+	}
+}
+
+// asTimeout memberi exit code exitTimeout bila err adalah timeout menunggu.
+func asTimeout(err error) error {
+	if errors.Is(err, mailtm.ErrTimeout) {
+		return &exitError{code: exitTimeout, err: fmt.Errorf("timeout: %w", err)}
+	}
+	return err
+}
+
+func cmdOTP(ctx context.Context, args []string) error {
+	fs := newFlagSet("otp")
+	account := fs.String("account", "", "key atau alamat akun")
+	timeout := fs.Duration("timeout", time.Duration(cfg.WaitTimeout), "batas waktu menunggu")
+	interval := fs.Duration("interval", time.Duration(cfg.PollInterval), "jeda antar pengecekan")
+	all := fs.Bool("all", false, "cetak semua kandidat kode, terbaik dulu")
+	poll := fs.Bool("poll", false, "jangan pakai hub Mercure (SSE), selalu polling")
+	filter := addWaitFilterFlags(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	wf, err := filter()
+	if err != nil {
+		return err
+	}
+	if !isSet(fs, "timeout") {
+		*timeout = time.Duration(cfg.WaitTimeout)
+	}
+	if !isSet(fs, "interval") {
+		*interval = time.Duration(cfg.PollInterval)
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	if *poll {
+		client.MercureURL = ""
+	}
+
+	// pesan tanpa kode (mis. sambutan) dilewati; tunggu pesan berikutnya
+	wf.Match = func(m *mailtm.Message) bool {
+		_, ok := mailtm.BestCode(m)
+		return ok
+	}
+	got, err := client.WaitForMessage(ctx, *timeout, *interval, wf)
+	if err != nil {
+		return asTimeout(err)
+	}
+
+	out := toMessageOut(got, false)
+	out.Codes = messageCodes(got)
+	emit("otp", out, func(w io.Writer) {
+		if !*all {
+			fmt.Fprintln(w, out.Codes[0])
+			return
+		}
+		for _, c := range out.Codes {
+			fmt.Fprintln(w, c)
+		}
+	})
+	return nil
+}
+
+// filterLinks menyaring tautan berdasarkan host dan/atau regex URL.
+func filterLinks(links []mailtm.Link, host string, re *regexp.Regexp) []mailtm.Link {
+	var out []mailtm.Link
+	for _, l := range links {
+		if host != "" && !mailtm.MatchHost(l.URL, host) {
+			continue
+		}
+		if re != nil && !re.MatchString(l.URL) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func cmdLinks(ctx context.Context, args []string) error {
+	fs := newFlagSet("links")
+	account := fs.String("account", "", "key atau alamat akun")
+	host := fs.String("host", "", "hanya tautan ke host ini (termasuk subdomain)")
+	match := fs.String("match", "", "hanya tautan yang cocok dengan regex ini")
+	first := fs.Bool("first", false, "cetak tautan pertama yang cocok saja")
+	wait := fs.Bool("wait", false, "tunggu pesan baru yang berisi tautan yang cocok")
+	timeout := fs.Duration("timeout", time.Duration(cfg.WaitTimeout), "batas waktu menunggu (dengan --wait)")
+	interval := fs.Duration("interval", time.Duration(cfg.PollInterval), "jeda antar pengecekan")
+	poll := fs.Bool("poll", false, "jangan pakai hub Mercure (SSE), selalu polling")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) > 1 || (*wait && len(pos) > 0) {
+		return errors.New("at most one message id is allowed, and none with --wait")
+	}
+	var re *regexp.Regexp
+	if *match != "" {
+		if re, err = regexp.Compile(*match); err != nil {
+			return fmt.Errorf("invalid --match: %w", err)
+		}
+	}
+	if !isSet(fs, "timeout") {
+		*timeout = time.Duration(cfg.WaitTimeout)
+	}
+	if !isSet(fs, "interval") {
+		*interval = time.Duration(cfg.PollInterval)
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	if *poll {
+		client.MercureURL = ""
+	}
+
+	var det *mailtm.Message
+	switch {
+	case *wait:
+		det, err = client.WaitForMessage(ctx, *timeout, *interval, mailtm.WaitFilter{Match: func(m *mailtm.Message) bool {
+			return len(filterLinks(mailtm.ExtractLinks(m), *host, re)) > 0
+		}})
+		err = asTimeout(err)
+	case len(pos) == 1:
+		det, err = client.GetMessage(ctx, pos[0])
+	default:
+		// tanpa ID: pesan terbaru
+		var msgs []mailtm.Message
+		if msgs, err = client.GetMessages(ctx); err == nil {
+			if len(msgs) == 0 {
+				return errors.New("inbox is empty")
+			}
+			det, err = client.GetMessage(ctx, msgs[0].ID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	links := filterLinks(mailtm.ExtractLinks(det), *host, re)
+	if len(links) == 0 {
+		return fmt.Errorf("no matching links in message %s", det.ID)
+	}
+	if *first {
+		links = links[:1]
+	}
+	items := make([]linkOut, len(links))
+	for i, l := range links {
+		items[i] = linkOut{URL: l.URL, Text: l.Text, Original: l.Original}
+	}
+	n := 0
+	emitList("link", items, []string{"NO", "URL", "TEKS"}, func(l linkOut) []string {
+		// plain hanya URL agar mudah dipakai skrip
+		if outFmt == outPlain {
+			return []string{l.URL}
+		}
+		n++
+		return []string{strconv.Itoa(n), l.URL, l.Text}
+	})
+	return nil
+}
+
+func cmdAttachments(ctx context.Context, args []string) error {
+	fs := newFlagSet("attachments")
+	account := fs.String("account", "", "key atau alamat akun")
+	save := fs.Bool("save", false, "simpan lampiran (semua, atau yang disebut setelah ID pesan)")
+	dir := fs.String("dir", ".", "folder tujuan (menyiratkan --save)")
+	force := fs.Bool("force", false, "timpa file yang sudah ada alih-alih menambah akhiran (n)")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return errors.New("a message id is required")
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	det, err := client.GetMessage(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	// lampiran dipilih lewat ID atau nama file
+	atts := det.Attachments
+	if want := pos[1:]; len(want) > 0 {
+		atts = nil
+		for _, w := range want {
+			i := slices.IndexFunc(det.Attachments, func(a mailtm.Attachment) bool { return a.ID == w || a.Filename == w })
+			if i < 0 {
+				return fmt.Errorf("attachment not found in message %s: %s", det.ID, w)
+			}
+			atts = append(atts, det.Attachments[i])
+		}
+	}
+
+	if !*save && !isSet(fs, "dir") {
+		items := make([]attachmentOut, len(atts))
+		for i, a := range atts {
+			items[i] = toAttachmentOut(a)
+		}
+		emitList("attachment", items, []string{"ID", "FILE", "TIPE", "UKURAN"}, func(a attachmentOut) []string {
+			return []string{a.ID, a.Filename, a.ContentType, humanSize(a.Size)}
+		})
+		return nil
+	}
+	if len(atts) == 0 {
+		return fmt.Errorf("message %s has no attachments", det.ID)
+	}
+	var items []attachmentOut
+	var errs []error
+	for _, a := range atts {
+		path, n, err := client.SaveAttachment(ctx, a, *dir, *force)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", nz(a.Filename, a.ID), err))
+			continue
+		}
+		o := toAttachmentOut(a)
+		o.Size, o.Path = n, path
+		items = append(items, o)
+	}
+	// plain hanya path agar mudah dipakai skrip
+	emitList("attachment", items, []string{"ID", "FILE", "UKURAN", "PATH"}, func(a attachmentOut) []string {
+		if outFmt == outPlain {
+			return []string{a.Path}
+		}
+		return []string{a.ID, a.Filename, humanSize(a.Size), a.Path}
+	})
+	return errors.Join(errs...)
+}
+
+func cmdSource(ctx context.Context, args []string) error {
+	fs := newFlagSet("source")
+	account := fs.String("account", "", "key atau alamat akun")
+	save := fs.Bool("save", false, "simpan sebagai <ID>.eml alih-alih mencetak")
+	dir := fs.String("dir", ".", "folder tujuan (menyiratkan --save)")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("exactly one message id is required")
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	src, err := client.GetSource(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	if !*save && !isSet(fs, "dir") {
+		emit("source", sourceOut{ID: pos[0], Data: src.Data}, func(w io.Writer) {
+			fmt.Fprint(w, src.Data)
+		})
+		return nil
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(*dir, mailtm.SafeFilename(pos[0]+".eml"))
+	if err := os.WriteFile(path, []byte(src.Data), 0644); err != nil {
+		return err
+	}
+	emit("source", sourceOut{ID: pos[0], Path: path}, func(w io.Writer) {
+		fmt.Fprintln(w, path)
+	})
+	return nil
+}
+
+func cmdMIME(ctx context.Context, args []string) error {
+	fs := newFlagSet("mime")
+	account := fs.String("account", "", "key atau alamat akun")
+	file := fs.String("file", "", "baca dari file .eml alih-alih dari server")
+	part := fs.String("part", "", "cetak isi bagian ini (mis. 1.2), sudah didekode")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*file == "") == (len(pos) != 1) {
+		return errors.New("exactly one of a message id or --file is required")
+	}
+	var raw io.Reader
+	id := ""
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		raw = f
+	} else {
+		id = pos[0]
+		client, err := openAccount(ctx, *account)
+		if err != nil {
+			return err
+		}
+		src, err := client.GetSource(ctx, id)
+		if err != nil {
+			return err
+		}
+		raw = strings.NewReader(src.Data)
+	}
+	pm, perr := mailtm.ParseMIME(raw)
+	if pm == nil {
+		return perr
+	}
+
+	if *part != "" {
+		p := pm.Find(*part)
+		if p == nil {
+			return fmt.Errorf("part not found: %s", *part)
+		}
+		if p.Body == nil {
+			return fmt.Errorf("part %s is a container; pick one of its children", *part)
+		}
+		out := toMIMEPartOut(0, p)
+		text := strings.HasPrefix(p.ContentType, "text/")
+		if text {
+			out.Body = p.Text()
+		} else {
+			out.BodyBase64 = base64.StdEncoding.EncodeToString(p.Body)
+		}
+		emit("mime_part", out, func(w io.Writer) {
+			if text {
+				fmt.Fprint(w, out.Body)
+			} else {
+				_, _ = w.Write(p.Body)
+			}
+		})
+		return perr
+	}
+
+	out := mimeOut{ID: id, Headers: []headerOut{}}
+	for _, h := range pm.Headers {
+		out.Headers = append(out.Headers, headerOut{Name: h.Name, Value: h.Value})
+	}
+	for depth, p := range pm.Root.Walk() {
+		out.Parts = append(out.Parts, toMIMEPartOut(depth, p))
+	}
+	emit("mime", out, func(w io.Writer) {
+		fmt.Fprintf(w, "HEADER (%d):\n", len(out.Headers))
+		for _, h := range out.Headers {
+			fmt.Fprintf(w, "%s: %s\n", h.Name, h.Value)
+		}
+		fmt.Fprintln(w, "\nSTRUKTUR:")
+		for _, p := range out.Parts {
+			line := strings.Repeat("  ", p.Depth) + nz(p.Path, "-") + " " + p.ContentType
+			if p.Charset != "" {
+				line += "; charset=" + p.Charset
+			}
+			if p.Filename != "" {
+				line += fmt.Sprintf(" %q", p.Filename)
+			}
+			if p.Disposition != "" {
+				line += " " + p.Disposition
+			}
+			if p.Encoding != "" {
+				line += " [" + p.Encoding + "]"
+			}
+			if !strings.HasPrefix(p.ContentType, "multipart/") {
+				line += " " + humanSize(int64(p.Size))
+			}
+			fmt.Fprintln(w, line)
+		}
+	})
+	return perr
+}
+
+func cmdWatch(ctx context.Context, args []string) error {
+	fs := newFlagSet("watch")
+	account := fs.String("account", "", "key atau alamat akun")
+	interval := fs.Duration("interval", time.Duration(cfg.PollInterval), "jeda antar pengecekan saat polling")
+	poll := fs.Bool("poll", false, "jangan pakai hub Mercure (SSE), selalu polling")
+	all := fs.Bool("all", false, "pantau semua akun tersimpan sekaligus")
+	tags := fs.String("tag", "", "pantau akun dengan salah satu tag ini (pisahkan dengan koma)")
+	parallel := fs.Int("parallel", 4, "batas request bersamaan saat memantau banyak akun")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !isSet(fs, "interval") {
+		*interval = time.Duration(cfg.PollInterval)
+	}
+	if *all || *tags != "" {
+		if *account != "" {
+			return errors.New("--account cannot be combined with --all or --tag")
+		}
+		return watchFeed(ctx, splitTags(*tags), watchOptions{Interval: *interval, Parallel: *parallel, Poll: *poll})
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	if *poll {
+		client.MercureURL = ""
+	}
+	if !outFmt.machine() {
+		fmt.Fprintf(os.Stderr, "Memantau %s... (Ctrl+C untuk berhenti)\n", client.Address)
+	}
+	err = client.WatchMessages(ctx, *interval, func(m mailtm.Message) error {
+		emit("message", toMessageOut(&m, false), func(w io.Writer) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.ID, nz(m.CreatedAt, "Unknown"), nz(m.From.Address, "Unknown"), nz(m.Subject, "No Subject"))
+		})
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// watchFeed mencetak pesan baru dari banyak akun sebagai satu feed, diberi
+// label nickname akun.
+func watchFeed(ctx context.Context, tags []string, opt watchOptions) error {
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	keys := selectAccountKeys(store, tags)
+	if len(keys) == 0 {
+		if len(tags) > 0 {
+			return fmt.Errorf("no accounts tagged %s", strings.Join(tags, ", "))
+		}
+		return errors.New("no accounts stored")
+	}
+	if !outFmt.machine() {
+		fmt.Fprintf(os.Stderr, "Memantau %d akun... (Ctrl+C untuk berhenti)\n", len(keys))
+	}
+	return watchAccounts(ctx, store, keys, opt, func(ev feedEvent) {
+		if ev.Err != nil {
+			reportError("watch", fmt.Errorf("%s: %w", ev.Label, ev.Err))
+			return
+		}
+		m := ev.Message
+		out := toMessageOut(&m, false)
+		out.Account = ev.Address
+		emit("message", out, func(w io.Writer) {
+			fmt.Fprintf(w, "[%s]\t%s\t%s\t%s\t%s\n", ev.Label, m.ID, nz(m.CreatedAt, "Unknown"), nz(m.From.Address, "Unknown"), nz(m.Subject, "No Subject"))
+		})
+	})
+}
+
+func cmdTag(ctx context.Context, args []string) error {
+	fs := newFlagSet("tag")
+	account := fs.String("account", "", "key atau alamat akun")
+	remove := fs.Bool("remove", false, "hapus tag alih-alih menambah")
+	tags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	key, err := resolveAccount(store, *account)
+	if err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		if err := store.SetTags(key, !*remove, tags...); err != nil {
+			return err
+		}
+	}
+	acc, _ := store.Get(key)
+	emit("account", toAccountOut(key, acc, false), func(w io.Writer) {
+		fmt.Fprintf(w, "Tag %s: %s\n", nz(acc.Nickname, acc.Address), nz(strings.Join(acc.Tags, ", "), "(tidak ada)"))
+	})
+	return nil
+}
+
+// cmdArchive meneruskan ke subperintah sync, list atau show.
+func cmdArchive(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "sync":
+			return cmdArchiveSync(ctx, args[1:])
+		case "list":
+			return cmdArchiveList(ctx, args[1:])
+		case "show":
+			return cmdArchiveShow(ctx, args[1:])
+		}
+		if !strings.HasPrefix(args[0], "-") {
+			return fmt.Errorf("unknown archive subcommand: %s (sync, list, show)", args[0])
+		}
+	}
+	// tanpa subperintah: -h menampilkan bantuan, selain itu error
+	if _, err := parseFlags(newFlagSet("archive"), args); err != nil {
+		return err
+	}
+	return errors.New("an archive subcommand is required: sync, list or show")
+}
+
+func cmdArchiveSync(ctx context.Context, args []string) error {
+	fs := newFlagSet("archive")
+	account := fs.String("account", "", "key atau alamat akun")
+	all := fs.Bool("all", false, "semua akun tersimpan")
+	tag := fs.String("tag", "", "akun dengan salah satu tag ini (dipisah koma)")
+	sources := fs.Bool("sources", false, "simpan juga sumber mentah (.eml)")
+	attachments := fs.Bool("attachments", false, "simpan juga isi lampiran")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	var keys []string
+	switch {
+	case *all || *tag != "":
+		if *account != "" {
+			return errors.New("--account cannot be combined with --all or --tag")
+		}
+		if keys = selectAccountKeys(store, splitTags(*tag)); len(keys) == 0 {
+			return errors.New("no accounts match")
+		}
+	default:
+		key, err := resolveAccount(store, *account)
+		if err != nil {
+			return err
+		}
+		keys = []string{key}
+	}
+
+	ar, err := openArchive(false)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	opt := archiveOptions{Sources: *sources, Attachments: *attachments}
+	var items []archiveSyncOut
+	var errs []error
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		acc, _ := store.Get(key)
+		if !outFmt.machine() {
+			fmt.Fprintf(os.Stderr, "Mengarsipkan %s...\n", acc.Address)
+		}
+		item := archiveSyncOut{Address: acc.Address}
+		client := newClient(store, sharedHTTP())
+		err := client.LoadAccount(ctx, key)
+		if err == nil {
+			var res archiveSyncResult
+			res, err = ar.Sync(ctx, client, opt)
+			item.New, item.Sources, item.Attachments, item.Total = res.New, res.Sources, res.Attachments, res.Total
+		}
+		if err != nil {
+			item.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", acc.Address, err))
+		}
+		items = append(items, item)
+	}
+	emitList("archive_sync", items, []string{"EMAIL", "BARU", "SUMBER", "LAMPIRAN", "TOTAL"}, func(s archiveSyncOut) []string {
+		total := strconv.Itoa(s.Total)
+		if s.Error != "" {
+			total = "error: " + s.Error
+		}
+		return []string{s.Address, strconv.Itoa(s.New), strconv.Itoa(s.Sources), strconv.Itoa(s.Attachments), total}
+	})
+	if err := ar.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// archiveAddress menerjemahkan --account untuk arsip: alamat yang punya arsip,
+// atau key/alamat akun tersimpan. Akun yang sudah dihapus hanya bisa disebut
+// lewat alamatnya.
+func archiveAddress(ar *Archive, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || ar.HasAccount(ref) {
+		return strings.ToLower(ref), nil
+	}
+	if store, err := loadStore(); err == nil {
+		if key, err := resolveAccount(store, ref); err == nil {
+			acc, _ := store.Get(key)
+			return strings.ToLower(acc.Address), nil
+		}
+	}
+	return "", fmt.Errorf("account not found in archive: %s", ref)
+}
+
+func cmdArchiveList(ctx context.Context, args []string) error {
+	fs := newFlagSet("archive")
+	account := fs.String("account", "", "alamat atau key akun; kosong = daftar akun di arsip")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	ar, err := openArchive(true)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	addr, err := archiveAddress(ar, *account)
+	if err != nil {
+		return err
+	}
+
+	if addr != "" {
+		msgs, err := ar.Messages(addr)
+		if err != nil {
+			return err
+		}
+		items := make([]messageOut, len(msgs))
+		for i := range msgs {
+			items[i] = toMessageOut(&msgs[i], false)
+			items[i].Account = addr
+		}
+		emitList("message", items, []string{"ID", "TANGGAL", "DARI", "SUBJEK"}, func(m messageOut) []string {
+			return []string{m.ID, nz(m.CreatedAt, "Unknown"), nz(m.From, "Unknown"), nz(m.Subject, "No Subject")}
+		})
+		return nil
+	}
+
+	accts, counts, err := ar.Accounts()
+	if err != nil {
+		return err
+	}
+	// akun yang tidak ada lagi di penyimpanan hanya tersisa di arsip
+	stored := map[string]bool{}
+	if store, err := loadStore(); err == nil {
+		for _, a := range store.All() {
+			stored[strings.ToLower(a.Address)] = true
+		}
+	}
+	items := make([]archiveAccountOut, len(accts))
+	for i, a := range accts {
+		items[i] = archiveAccountOut{
+			Address: a.Address, AccountID: a.AccountID, Nickname: a.Nickname, Tags: a.Tags,
+			Messages: counts[a.Address], Stored: stored[strings.ToLower(a.Address)],
+		}
+		if !a.LastSync.IsZero() {
+			items[i].LastSync = a.LastSync.UTC().Format(time.RFC3339)
+		}
+	}
+	emitList("archive_account", items, []string{"EMAIL", "NICKNAME", "PESAN", "SINKRON TERAKHIR", "STATUS"}, func(a archiveAccountOut) []string {
+		last := a.LastSync
+		if outFmt != outPlain {
+			t, _ := time.Parse(time.RFC3339, a.LastSync)
+			last = ago(t)
+		}
+		status := "tersimpan"
+		if !a.Stored {
+			status = "hanya arsip"
+		}
+		return []string{a.Address, nz(a.Nickname, "-"), strconv.Itoa(a.Messages), nz(last, "-"), status}
+	})
+	return nil
+}
+
+func cmdArchiveShow(ctx context.Context, args []string) error {
+	fs := newFlagSet("archive")
+	account := fs.String("account", "", "alamat atau key akun (wajib bila ID ada di lebih dari satu akun)")
+	html := fs.Bool("html", false, "tampilkan isi HTML")
+	source := fs.Bool("source", false, "cetak sumber mentah yang diarsipkan")
+	save := fs.String("save", "", "simpan lampiran yang diarsipkan ke folder ini")
+	force := fs.Bool("force", false, "timpa file yang sudah ada saat --save")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return errors.New("exactly one message id is required")
+	}
+	ar, err := openArchive(true)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	addr, err := archiveAddress(ar, *account)
+	if err != nil {
+		return err
+	}
+	m, addr, err := ar.Message(addr, pos[0])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *source:
+		data, ok, err := ar.Source(addr, m.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("source of %s is not archived (run 'mailtm archive sync --sources')", m.ID)
+		}
+		emit("source", sourceOut{ID: m.ID, Data: string(data)}, func(w io.Writer) {
+			_, _ = w.Write(data)
+		})
+		return nil
+
+	case *save != "":
+		if len(m.Attachments) == 0 {
+			return fmt.Errorf("message %s has no attachments", m.ID)
+		}
+		if err := os.MkdirAll(*save, 0755); err != nil {
+			return err
+		}
+		var items []attachmentOut
+		var errs []error
+		for _, a := range m.Attachments {
+			data, ok, err := ar.Attachment(addr, m.ID, a.ID)
+			if err == nil && !ok {
+				err = errors.New("not archived (run 'mailtm archive sync --attachments')")
+			}
+			var path string
+			var n int64
+			if err == nil {
+				path, n, err = saveFile(bytes.NewReader(data), *save, a.Filename, *force)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", nz(a.Filename, a.ID), err))
+				continue
+			}
+			o := toAttachmentOut(a)
+			o.Size, o.Path = n, path
+			items = append(items, o)
+		}
+		emitList("attachment", items, []string{"ID", "FILE", "UKURAN", "PATH"}, func(a attachmentOut) []string {
+			if outFmt == outPlain {
+				return []string{a.Path}
+			}
+			return []string{a.ID, a.Filename, humanSize(a.Size), a.Path}
+		})
+		return errors.Join(errs...)
+	}
+
+	printMessage(m, *html)
+	return nil
+}
+
+func cmdSearch(ctx context.Context, args []string) error {
+	fs := newFlagSet("search")
+	offline := fs.Bool("offline", false, "cari di arsip saja, tanpa mengambil pesan baru dari server")
+	syncArchive := fs.Bool("sync", false, "simpan pesan yang diambil dari server ke arsip")
+	limit := fs.Int("limit", 20, "jumlah hasil maksimum, 0 = semua")
+	flags, terms := splitSearchArgs(fs, args)
+	pos, err := parseFlags(fs, flags)
+	if err != nil {
+		return err
+	}
+	if pos = append(pos, terms...); len(pos) == 0 {
+		return errors.New("a search query is required")
+	}
+	if *offline && *syncArchive {
+		return errors.New("--offline and --sync cannot be used together")
+	}
+	q, err := parseSearchQuery(strings.Join(pos, " "))
+	if err != nil {
+		return err
+	}
+	// arsip hanya ditulis bila diminta dengan --sync
+	ar, err := openArchive(!*syncArchive)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	var live []liveMessages
+	if !*offline {
+		store, err := loadStore()
+		if err != nil {
+			return err
+		}
+		keys := searchAccountKeys(store, q)
+		var errs []error
+		if *syncArchive {
+			// pesan baru masuk ke arsip dulu agar ikut terindeks
+			errs = refreshArchive(ctx, ar, store, keys)
+		} else {
+			live, errs = fetchMessages(ctx, ar, store, keys, q)
+		}
+		for _, err := range errs {
+			if !outFmt.machine() {
+				fmt.Fprintln(os.Stderr, "peringatan:", err)
+			}
+		}
+	}
+	ix, err := buildSearchIndex(ar, live)
+	if err != nil {
+		return err
+	}
+	hits := ix.Search(q)
+	if !outFmt.machine() {
+		fmt.Fprintf(os.Stderr, "%d dari %d pesan cocok\n", len(hits), ix.Len())
+	}
+	if *limit > 0 && len(hits) > *limit {
+		hits = hits[:*limit]
+	}
+	items := make([]searchHitOut, len(hits))
+	for i, h := range hits {
+		items[i] = searchHitOut{messageOut: toMessageOut(h.Doc.Msg, false), Score: math.Round(h.Score*1000) / 1000, Snippet: h.snippet(q, 120)}
+		items[i].Account = h.Doc.Account
+	}
+	emitList("search_result", items, []string{"ID", "AKUN", "TANGGAL", "DARI", "SUBJEK"}, func(h searchHitOut) []string {
+		row := []string{h.ID, h.Account, nz(h.CreatedAt, "Unknown"), nz(h.From, "Unknown"), nz(h.Subject, "No Subject")}
+		if outFmt == outPlain {
+			return append(row, strconv.FormatFloat(h.Score, 'f', 3, 64))
+		}
+		return row
+	})
+	return nil
+}
+
+// splitSearchArgs memisahkan flag dari query, sehingga kata pengecualian
+// seperti -newsletter tidak dianggap flag yang tidak dikenal. Semua sesudah
+// "--" termasuk query.
+func splitSearchArgs(fs *flag.FlagSet, args []string) (flags, terms []string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			return flags, append(terms, args[i+1:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		f := fs.Lookup(name)
+		if !strings.HasPrefix(a, "-") || len(a) == 1 || (f == nil && name != "h" && name != "help") {
+			terms = append(terms, a)
+			continue
+		}
+		flags = append(flags, a)
+		if f == nil || hasValue || i+1 == len(args) {
+			continue
+		}
+		// nilai flag non-boolean boleh di argumen berikutnya
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return flags, terms
+}
+
+func cmdDeleteMessage(ctx context.Context, args []string) error {
+	fs := newFlagSet("delete-message")
+	account := fs.String("account", "", "key atau alamat akun")
+	all := fs.Bool("all", false, "hapus semua pesan")
+	ids, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if !*all && len(ids) == 0 {
+		return errors.New("message id or --all is required")
+	}
+	client, err := openAccount(ctx, *account)
+	if err != nil {
+		return err
+	}
+	var cnt int
+	if *all {
+		cnt, err = client.DeleteAllMessages(ctx)
+	} else {
+		var errs []error
+		for _, id := range ids {
+			if err := client.DeleteMessage(ctx, id); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+				continue
+			}
+			cnt++
+		}
+		err = errors.Join(errs...)
+	}
+	emitResult(resultOut{Action: "delete-message", Key: client.AccountKey, Count: cnt},
+		fmt.Sprintf("%d pesan berhasil dihapus.", cnt))
+	return err
+}
+
+func cmdDeleteAccount(ctx context.Context, args []string) error {
+	fs := newFlagSet("delete-account")
+	account := fs.String("account", "", "key atau alamat akun")
+	local := fs.Bool("local", false, "hanya hapus dari penyimpanan lokal")
+	archive := fs.Bool("archive", false, "salin semua pesan ke arsip lokal dulu; akun tidak dihapus bila gagal")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	key, err := resolveAccount(store, *account)
+	if err != nil {
+		return err
+	}
+	acc, _ := store.Get(key)
+	var client *Client
+	if !*local || *archive {
+		client = NewClient("", cfg.StorePath)
+		if err := client.LoadAccount(ctx, key); err != nil {
+			return err
+		}
+	}
+	if *archive {
+		res, err := archiveBeforeDelete(ctx, client)
+		if err != nil {
+			return fmt.Errorf("archive failed, account not deleted: %w", err)
+		}
+		if !outFmt.machine() {
+			fmt.Fprintf(os.Stderr, "%d pesan baru diarsipkan (%d total).\n", res.New, res.Total)
+		}
+	}
+	if !*local {
+		if err := client.Delete(ctx, true); err != nil {
+			return err
+		}
+		emitResult(resultOut{Action: "delete-account", Key: key, Address: acc.Address, Detail: "server"},
+			fmt.Sprintf("Akun %s berhasil dihapus dari server dan penyimpanan.", acc.Address))
+		return nil
+	}
+	if !store.Remove(key) {
+		return fmt.Errorf("could not remove %s from local storage", key)
+	}
+	emitResult(resultOut{Action: "delete-account", Key: key, Address: acc.Address, Detail: "local"},
+		fmt.Sprintf("Akun %s berhasil dihapus dari penyimpanan lokal.", acc.Address))
+	return nil
+}
+
+func cmdRename(ctx context.Context, args []string) error {
+	fs := newFlagSet("rename")
+	account := fs.String("account", "", "key atau alamat akun")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	newNick := strings.TrimSpace(strings.Join(pos, " "))
+	if newNick == "" {
+		return errors.New("new nickname is required")
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	key, err := resolveAccount(store, *account)
+	if err != nil {
+		return err
+	}
+	if err := store.SetNickname(key, newNick); err != nil {
+		return err
+	}
+	acc, _ := store.Get(key)
+	emit("account", toAccountOut(key, acc, false), func(w io.Writer) {
+		fmt.Fprintln(w, "Nickname berhasil diubah menjadi:", newNick)
+	})
+	return nil
+}
+
+func cmdEncrypt(ctx context.Context, args []string) error {
+	fs := newFlagSet("encrypt")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	if store.Encrypted() {
+		return errors.New("store is already encrypted; use rekey to change the passphrase")
+	}
+	pass, err := newPassphrase("MAILTM_PASSPHRASE")
+	if err != nil {
+		return err
+	}
+	if err := store.Encrypt(pass); err != nil {
+		return err
+	}
+	emitResult(resultOut{Action: "encrypt", Count: len(store.All())},
+		fmt.Sprintf("Penyimpanan %s berhasil dienkripsi (%d akun).", store.File, len(store.All())))
+	return nil
+}
+
+func cmdDecrypt(ctx context.Context, args []string) error {
+	fs := newFlagSet("decrypt")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	if !store.Encrypted() {
+		return errors.New("store is not encrypted")
+	}
+	if err := store.Decrypt(); err != nil {
+		return err
+	}
+	emitResult(resultOut{Action: "decrypt", Count: len(store.All())},
+		fmt.Sprintf("Penyimpanan %s disimpan tanpa enkripsi (%d akun).", store.File, len(store.All())))
+	return nil
+}
+
+func cmdRekey(ctx context.Context, args []string) error {
+	fs := newFlagSet("rekey")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	store, err := loadStore()
+	if err != nil {
+		return err
+	}
+	if !store.Encrypted() {
+		return errors.New("store is not encrypted; use encrypt first")
+	}
+	pass, err := newPassphrase("MAILTM_NEW_PASSPHRASE")
+	if err != nil {
+		return err
+	}
+	if err := store.Encrypt(pass); err != nil {
+		return err
+	}
+	emitResult(resultOut{Action: "rekey", Count: len(store.All())},
+		fmt.Sprintf("Passphrase penyimpanan %s berhasil diganti.", store.File))
+	return nil
+}
+
+func cmdConfig(ctx context.Context, args []string) error {
+	fs := newFlagSet("config")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	type configOut struct {
+		ConfigFile string `json:"config_file"`
+		Config
+	}
+	emit("config", configOut{ConfigFile: cfg.file, Config: cfg}, func(w io.Writer) {
+		fmt.Fprintln(w, "File config:", nz(cfg.file, "(tidak ada)"))
+		fmt.Fprintln(w, "File akun:", cfg.StorePath)
+		fmt.Fprintln(w, "File arsip:", archivePath())
+		fmt.Fprintln(w, "Base URL:", cfg.BaseURL)
+		fmt.Fprintln(w, "Mercure URL:", nz(cfg.MercureURL, "(polling)"))
+		fmt.Fprintln(w, "HTTP timeout:", time.Duration(cfg.HTTPTimeout))
+		fmt.Fprintln(w, "Interval polling:", time.Duration(cfg.PollInterval))
+		fmt.Fprintln(w, "Timeout tunggu:", time.Duration(cfg.WaitTimeout))
+		fmt.Fprintln(w, "Domain bawaan:", nz(cfg.DefaultDomain, "(otomatis)"))
+		fmt.Fprintf(w, "Batas request: %g/detik, %d percobaan ulang\n", cfg.RateLimit, cfg.Retries)
+	})
+	return nil
+}
+
+func cmdFakeServer(ctx context.Context, args []string) error {
+	fs := newFlagSet("fake-server")
+	listen := fs.String("listen", "127.0.0.1:8025", "alamat listen")
+	domains := fs.String("domains", mailtmtest.DefaultDomain, "daftar domain dipisah koma")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	var doms []string
+	for _, d := range strings.Split(*domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			doms = append(doms, d)
+		}
+	}
+	if len(doms) == 0 {
+		return errors.New("at least one domain is required")
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler: mailtmtest.NewFake(doms...).Handler(),
+		// stream SSE ikut berhenti saat Ctrl+C agar Shutdown tidak menunggu
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	base := "http://" + ln.Addr().String()
+	type serverOut struct {
+		BaseURL    string   `json:"base_url"`
+		MercureURL string   `json:"mercure_url"`
+		Domains    []string `json:"domains"`
+	}
+	emit("server", serverOut{BaseURL: base, MercureURL: base + "/.well-known/mercure", Domains: doms}, func(w io.Writer) {
+		fmt.Fprintln(w, "Server Mail.tm palsu berjalan di", base)
+		fmt.Fprintln(w, "\nArahkan mailtm ke server ini:")
+		fmt.Fprintf(w, "  export MAILTM_BASE_URL=%s\n", base)
+		fmt.Fprintf(w, "  export MAILTM_MERCURE_URL=%s/.well-known/mercure\n", base)
+		fmt.Fprintln(w, "\nKirim pesan ke akun yang sudah dibuat:")
+		fmt.Fprintf(w, "  curl -d '{\"to\":\"user@%s\",\"subject\":\"Halo\",\"text\":\"Kode: 123456\"}' %s/_fake/deliver\n", doms[0], base)
+		fmt.Fprintln(w, "\nCtrl+C untuk berhenti.")
+	})
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return srv.Close()
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+	"github.com/luzyver/Mail.TM-CLI/mailtm/mailtmtest"
+)
+
+// newCLIFake menjalankan server palsu dan mengarahkan konfigurasi CLI ke sana
+// dengan file akun di direktori sementara.
+func newCLIFake(t *testing.T) *mailtmtest.Server {
+	t.Helper()
+	srv := mailtmtest.NewServer()
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("MAILTM_CONFIG", "")
+	t.Setenv("MAILTM_PASSPHRASE", "")
+	t.Setenv("MAILTM_BASE_URL", srv.URL)
+	t.Setenv("MAILTM_MERCURE_URL", srv.MercureURL())
+	t.Setenv("MAILTM_STORE", filepath.Join(dir, "email_accounts.json"))
+	t.Setenv("MAILTM_ARCHIVE", filepath.Join(dir, "archive.db"))
+	t.Setenv("MAILTM_POLL_INTERVAL", "50ms")
+	return srv
+}
+
+// runMT menjalankan CLI seperti dari shell dan mengembalikan stdout serta
+// exit code-nya.
+func runMT(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cfg, outFmt = defaultConfig(), outPlain
+	if err := loadConfig(args); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, out
+	code := runCLI(args)
+	os.Stdout, os.Stderr = stdout, stderr
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), code
+}
+
+func decodeEnvelope[T any](t *testing.T, s string) T {
+	t.Helper()
+	var env struct {
+		Kind string `json:"kind"`
+		Data T      `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(s), &env); err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return env.Data
+}
+
+func TestCLICreateInboxRead(t *testing.T) {
+	srv := newCLIFake(t)
+
+	out, code := runMT(t, "-o", "json", "create", "--username", "bob", "--nickname", "utama")
+	if code != 0 {
+		t.Fatalf("create: exit %d: %s", code, out)
+	}
+	acct := decodeEnvelope[accountOut](t, out)
+	if acct.Address != "bob@"+mailtmtest.DefaultDomain || acct.Key != "utama" || acct.Password == "" {
+		t.Fatalf("create = %+v", acct)
+	}
+
+	sent, err := srv.Deliver(acct.Address, mailtm.Message{Subject: "Verifikasi", Text: "Kode anda: 731904"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, code = runMT(t, "-o", "json", "inbox", "--account", "utama")
+	if code != 0 {
+		t.Fatalf("inbox: exit %d: %s", code, out)
+	}
+	msgs := decodeEnvelope[[]messageOut](t, out)
+	if len(msgs) != 1 || msgs[0].ID != sent.ID || msgs[0].Subject != "Verifikasi" {
+		t.Fatalf("inbox = %+v", msgs)
+	}
+
+	out, code = runMT(t, "read", "--account", "utama", sent.ID)
+	if code != 0 || !strings.Contains(out, "Kode anda: 731904") {
+		t.Fatalf("read: exit %d: %s", code, out)
+	}
+	out, code = runMT(t, "otp", "--account", "utama", "--since", "1m", "--timeout", "2s")
+	if code != 0 || strings.TrimSpace(out) != "731904" {
+		t.Fatalf("otp: exit %d: %q", code, out)
+	}
+}
+
+func TestCLIWaitTimeout(t *testing.T) {
+	newCLIFake(t)
+	if out, code := runMT(t, "create", "--username", "sepi"); code != 0 {
+		t.Fatalf("create: exit %d: %s", code, out)
+	}
+	if out, code := runMT(t, "wait", "--timeout", "200ms"); code != exitTimeout {
+		t.Fatalf("wait: exit %d, want %d: %s", code, exitTimeout, out)
+	}
+}
+
+func TestCLISearchDoesNotWriteArchive(t *testing.T) {
+	srv := newCLIFake(t)
+	if out, code := runMT(t, "create", "--username", "cari"); code != 0 {
+		t.Fatalf("create: exit %d: %s", code, out)
+	}
+	if _, err := srv.Deliver("cari@"+mailtmtest.DefaultDomain, mailtm.Message{Subject: "Faktur Maret", Text: "tagihan listrik"}); err != nil {
+		t.Fatal(err)
+	}
+	archive := os.Getenv("MAILTM_ARCHIVE")
+
+	out, code := runMT(t, "-o", "json", "search", "tagihan")
+	if hits := decodeEnvelope[[]searchHitOut](t, out); code != 0 || len(hits) != 1 || hits[0].Subject != "Faktur Maret" {
+		t.Fatalf("search: exit %d: %s", code, out)
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Fatalf("search without --sync created the archive: %v", err)
+	}
+	if out, _ := runMT(t, "-o", "json", "search", "--offline", "tagihan"); len(decodeEnvelope[[]searchHitOut](t, out)) != 0 {
+		t.Fatalf("offline search found messages without an archive: %s", out)
+	}
+
+	if out, code := runMT(t, "-o", "json", "search", "--sync", "tagihan"); code != 0 || len(decodeEnvelope[[]searchHitOut](t, out)) != 1 {
+		t.Fatalf("search --sync: exit %d: %s", code, out)
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Fatalf("search --sync did not write the archive: %v", err)
+	}
+	out, code = runMT(t, "-o", "json", "search", "--offline", "tagihan")
+	if hits := decodeEnvelope[[]searchHitOut](t, out); code != 0 || len(hits) != 1 {
+		t.Fatalf("offline search after --sync: exit %d: %s", code, out)
+	}
+	// pesan yang sudah diarsipkan tidak muncul dua kali
+	out, _ = runMT(t, "-o", "json", "search", "tagihan")
+	if hits := decodeEnvelope[[]searchHitOut](t, out); len(hits) != 1 {
+		t.Fatalf("search after --sync: %s", out)
+	}
+	if _, code := runMT(t, "search", "--offline", "--sync", "tagihan"); code == 0 {
+		t.Fatal("--offline --sync accepted")
+	}
+}
+
+// account: dicocokkan juga dengan key akun, yang berbeda dari nickname bila
+// nickname-nya bentrok (kerja, kerja_1).
+func TestCLISearchAccountKey(t *testing.T) {
+	srv := newCLIFake(t)
+	for _, user := range []string{"satu", "dua"} {
+		if out, code := runMT(t, "create", "--username", user, "--nickname", "kerja"); code != 0 {
+			t.Fatalf("create: exit %d: %s", code, out)
+		}
+		if _, err := srv.Deliver(user+"@"+mailtmtest.DefaultDomain, mailtm.Message{Subject: "Tagihan " + user}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"search", "account:kerja_1", "tagihan"},
+		{"search", "--sync", "account:kerja_1", "tagihan"},
+		{"search", "--offline", "account:kerja_1", "tagihan"},
+	} {
+		out, code := runMT(t, append([]string{"-o", "json"}, args...)...)
+		hits := decodeEnvelope[[]searchHitOut](t, out)
+		if code != 0 || len(hits) != 1 || hits[0].Subject != "Tagihan dua" {
+			t.Fatalf("%v: exit %d: %s", args, code, out)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+// ========================= Konfigurasi =========================
+
+// Urutan prioritas: bawaan < file config < env MAILTM_* < flag.
+
+const (
+	storeFileName  = "email_accounts.json"
+	legacyFileName = "email_account.json" // format lama satu akun
+)
+
+type duration time.Duration
+
+// duration di file config boleh berupa string ("30s") atau angka detik.
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = duration(v)
+		return nil
+	}
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return fmt.Errorf("invalid duration %s", b)
+	}
+	*d = duration(secs * float64(time.Second))
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+type Config struct {
+	StorePath     string   `json:"store_path"`
+	ArchivePath   string   `json:"archive_path"` // kosong = archive.db di dataDir
+	BaseURL       string   `json:"base_url"`
+	MercureURL    string   `json:"mercure_url"`
+	HTTPTimeout   duration `json:"http_timeout"`
+	PollInterval  duration `json:"poll_interval"`
+	WaitTimeout   duration `json:"wait_timeout"`
+	DefaultDomain string   `json:"default_domain"`
+	RateLimit     float64  `json:"rate_limit"` // request per detik, 0 = tanpa batas
+	Retries       int      `json:"retries"`    // percobaan ulang untuk 429/5xx
+
+	file string // file config yang dipakai, kosong jika tidak ada
+}
+
+var cfg = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		StorePath:    defaultStorePath(),
+		BaseURL:      "https://api.mail.tm",
+		MercureURL:   mailtm.DefaultMercureURL,
+		HTTPTimeout:  duration(30 * time.Second),
+		PollInterval: duration(5 * time.Second),
+		WaitTimeout:  duration(30 * time.Second),
+		RateLimit:    mailtm.DefaultRateLimit,
+		Retries:      mailtm.DefaultRetries,
+	}
+}
+
+// configDir mengikuti XDG_CONFIG_HOME (atau padanannya di Windows/macOS).
+func configDir() string {
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "mailtm")
+	}
+	return ""
+}
+
+// dataDir mengikuti XDG_DATA_HOME; default ~/.local/share/mailtm.
+func dataDir() string {
+	if d := os.Getenv("XDG_DATA_HOME"); d != "" {
+		return filepath.Join(d, "mailtm")
+	}
+	switch runtime.GOOS {
+	case "windows":
+		if d := os.Getenv("LocalAppData"); d != "" {
+			return filepath.Join(d, "mailtm")
+		}
+	case "darwin":
+		if h, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(h, "Library", "Application Support", "mailtm")
+		}
+	}
+	if h, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(h, ".local", "share", "mailtm")
+	}
+	return ""
+}
+
+// defaultStorePath tetap memakai email_accounts.json di direktori kerja jika
+// sudah ada, agar pengguna versi lama tidak kehilangan akunnya.
+func defaultStorePath() string {
+	if _, err := os.Stat(storeFileName); err == nil {
+		return storeFileName
+	}
+	if d := dataDir(); d != "" {
+		return filepath.Join(d, storeFileName)
+	}
+	return storeFileName
+}
+
+// legacyAccountFile mencari email_account.json di sebelah file akun atau di
+// direktori kerja; kosong jika tidak ada.
+func legacyAccountFile() string {
+	for _, p := range []string{filepath.Join(filepath.Dir(cfg.StorePath), legacyFileName), legacyFileName} {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadConfig membaca file config dan env. Flag --config diambil lebih dulu
+// dari args karena menentukan file mana yang dibaca; flag lain diterapkan
+// saat parsing perintah.
+func loadConfig(args []string) error {
+	path, explicit := os.Getenv("MAILTM_CONFIG"), false
+	if path != "" {
+		explicit = true
+	}
+	if p := scanFlag(args, "config"); p != "" {
+		path, explicit = p, true
+	}
+	if path == "" && configDir() != "" {
+		path = filepath.Join(configDir(), "config.json")
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, &cfg); err != nil {
+				return fmt.Errorf("config %s: %w", path, err)
+			}
+			cfg.file = path
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	return cfg.applyEnv()
+}
+
+func (c *Config) applyEnv() error {
+	str := map[string]*string{
+		"MAILTM_STORE":       &c.StorePath,
+		"MAILTM_ARCHIVE":     &c.ArchivePath,
+		"MAILTM_BASE_URL":    &c.BaseURL,
+		"MAILTM_MERCURE_URL": &c.MercureURL,
+		"MAILTM_DOMAIN":      &c.DefaultDomain,
+	}
+	for env, p := range str {
+		if v, ok := os.LookupEnv(env); ok {
+			*p = v
+		}
+	}
+	dur := map[string]*duration{
+		"MAILTM_HTTP_TIMEOUT":  &c.HTTPTimeout,
+		"MAILTM_POLL_INTERVAL": &c.PollInterval,
+		"MAILTM_WAIT_TIMEOUT":  &c.WaitTimeout,
+	}
+	for env, p := range dur {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", env, err)
+			}
+			*p = duration(d)
+		}
+	}
+	if v := os.Getenv("MAILTM_RATE_LIMIT"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("MAILTM_RATE_LIMIT: %w", err)
+		}
+		c.RateLimit = f
+	}
+	if v := os.Getenv("MAILTM_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("MAILTM_RETRIES: %w", err)
+		}
+		c.Retries = n
+	}
+	return nil
+}
+
+// scanFlag mencari nilai --name / --name=v / -name v sebelum parsing penuh.
+func scanFlag(args []string, name string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		trimmed := strings.TrimLeft(a, "-")
+		if trimmed == a {
+			continue
+		}
+		if v, ok := strings.CutPrefix(trimmed, name+"="); ok {
+			return v
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// addConfigFlags mengikat flag global langsung ke cfg, sehingga nilainya
+// menimpa file config dan env.
+func addConfigFlags(fs *flag.FlagSet) {
+	var ignored string
+	fs.StringVar(&ignored, "config", cfg.file, "file config JSON (env MAILTM_CONFIG)")
+	fs.StringVar(&cfg.StorePath, "store", cfg.StorePath, "file penyimpanan akun (env MAILTM_STORE)")
+	fs.StringVar(&cfg.ArchivePath, "archive-file", cfg.ArchivePath, "file arsip pesan lokal (env MAILTM_ARCHIVE)")
+	fs.StringVar(&cfg.BaseURL, "base-url", cfg.BaseURL, "URL API Mail.tm (env MAILTM_BASE_URL)")
+	fs.StringVar(&cfg.MercureURL, "mercure-url", cfg.MercureURL, "URL hub Mercure, kosong = polling (env MAILTM_MERCURE_URL)")
+	fs.Var(durationFlag{&cfg.HTTPTimeout}, "http-timeout", "timeout request HTTP (env MAILTM_HTTP_TIMEOUT)")
+	fs.Var(durationFlag{&cfg.PollInterval}, "poll-interval", "jeda polling pesan baru (env MAILTM_POLL_INTERVAL)")
+	fs.Var(durationFlag{&cfg.WaitTimeout}, "wait-timeout", "batas waktu bawaan menunggu pesan (env MAILTM_WAIT_TIMEOUT)")
+	fs.StringVar(&cfg.DefaultDomain, "domain", cfg.DefaultDomain, "domain untuk akun baru, 'random' = acak (env MAILTM_DOMAIN)")
+	fs.Float64Var(&cfg.RateLimit, "rate-limit", cfg.RateLimit, "batas request per detik, 0 = tanpa batas (env MAILTM_RATE_LIMIT)")
+	fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "percobaan ulang untuk 429/5xx (env MAILTM_RETRIES)")
+}
+
+type durationFlag struct{ d *duration }
+
+func (f durationFlag) String() string {
+	if f.d == nil {
+		return ""
+	}
+	return time.Duration(*f.d).String()
+}
+
+func (f durationFlag) Set(v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*f.d = duration(d)
+	return nil
+}
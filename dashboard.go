@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ========================= Dashboard =========================
+
+// accountStats adalah ringkasan kotak masuk satu akun.
+type accountStats struct {
+	Total int
+	// Unread dihitung dari halaman pertama saja; UnreadMore berarti masih
+	// ada halaman lain sehingga angka sebenarnya bisa lebih besar.
+	Unread      int
+	UnreadMore  bool
+	LastSubject string
+	LastAt      time.Time
+	Err         error
+	fetched     time.Time
+}
+
+// statsTTL: ringkasan dipakai ulang selama ini agar menu tidak menghubungi
+// server setiap kali digambar ulang.
+const statsTTL = 30 * time.Second
+
+// statsParallel membatasi akun yang diambil ringkasannya bersamaan.
+const statsParallel = 6
+
+type statsCache struct {
+	mu sync.Mutex
+	m  map[string]accountStats
+}
+
+var dashboardCache = &statsCache{m: map[string]accountStats{}}
+
+// invalidate membuang ringkasan akun, mis. setelah pesannya dibaca atau
+// dihapus.
+func (c *statsCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, key)
+}
+
+// get mengembalikan ringkasan untuk keys, mengambil yang belum ada atau
+// kedaluwarsa secara bersamaan. Error per akun disimpan di Err, tidak
+// menggagalkan akun lain.
+func (c *statsCache) get(ctx context.Context, store *Storage, keys []string) map[string]accountStats {
+	out := make(map[string]accountStats, len(keys))
+	var missing []string
+	c.mu.Lock()
+	for _, k := range keys {
+		if st, ok := c.m[k]; ok && time.Since(st.fetched) < statsTTL {
+			out[k] = st
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, statsParallel)
+	for _, k := range missing {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			st := fetchStats(ctx, store, k)
+			mu.Lock()
+			out[k] = st
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	for _, k := range missing {
+		// error tidak di-cache supaya langsung dicoba lagi
+		if out[k].Err == nil {
+			c.m[k] = out[k]
+		}
+	}
+	c.mu.Unlock()
+	return out
+}
+
+func fetchStats(ctx context.Context, store *Storage, key string) accountStats {
+	client := newClient(store, sharedHTTP())
+	if err := client.LoadAccount(ctx, key); err != nil {
+		return accountStats{Err: err}
+	}
+	p, err := client.GetMessagesPage(ctx, 1)
+	if err != nil {
+		return accountStats{Err: err}
+	}
+	st := accountStats{Total: max(p.TotalItems, len(p.Messages)), UnreadMore: p.Next > 0, fetched: time.Now()}
+	for _, m := range p.Messages {
+		if !m.Seen {
+			st.Unread++
+		}
+	}
+	if len(p.Messages) > 0 {
+		st.LastSubject = nz(p.Messages[0].Subject, "No Subject")
+		st.LastAt, _ = time.Parse(time.RFC3339, p.Messages[0].CreatedAt)
+	}
+	return st
+}
+
+// unreadLabel menampilkan jumlah belum dibaca, dengan "+" bila bisa lebih.
+func (st accountStats) unreadLabel() string {
+	if st.UnreadMore {
+		return fmt.Sprintf("%d+", st.Unread)
+	}
+	return fmt.Sprint(st.Unread)
+}
+
+// ago menampilkan selisih waktu yang mudah dibaca, mis. "5 menit lalu".
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "baru saja"
+	case d < time.Hour:
+		return fmt.Sprintf("%d menit lalu", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d jam lalu", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d hari lalu", int(d.Hours()/24))
+}
@@ -0,0 +1,3 @@
+module github.com/luzyver/Mail.TM-CLI
+
+go 1.24
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========================= Key-value store =========================
+
+// kvStore adalah penyimpanan key-value sederhana berbasis log append-only:
+// setiap Put/Delete ditambahkan di akhir file, sedangkan indeks key -> offset
+// hanya ada di memori dan dibangun ulang saat dibuka. Selama terbuka untuk
+// ditulis, file dikunci agar tidak ditulis dua proses sekaligus.
+//
+// Format file: magic, lalu rekaman berurutan
+//
+//	crc header | panjang key | panjang value (bit teratas = tombstone) | crc isi | key | value
+//
+// Kedua crc32 (IEEE): crc header menutupi tiga field sesudahnya, crc isi
+// menutupi key dan value. Dengan crc header, posisi acak di bagian rusak
+// sudah ditolak dari 16 byte tanpa membaca isinya.
+type kvStore struct {
+	mu       sync.RWMutex
+	path     string
+	f        *os.File // nil bila store hanya-baca dan filenya belum ada
+	readOnly bool
+	closed   bool
+	unlock   func()
+	size     int64              // akhir rekaman valid terakhir
+	torn     int64              // byte rekaman terpotong di ujung file
+	index    map[string]kvEntry // posisi value terbaru tiap key
+	stale    int64              // byte rekaman yang sudah tertimpa atau dihapus
+
+	// Damaged adalah jumlah byte rusak di tengah file yang dilewati saat
+	// dibuka; rekaman sesudahnya tetap terbaca.
+	Damaged int64
+	// Backup adalah salinan file sebelum diperbaiki, kosong bila file utuh.
+	Backup string
+}
+
+type kvEntry struct {
+	off int64 // offset awal rekaman
+	kn  uint32
+	vn  uint32
+}
+
+const (
+	kvMagic     = "MTKV\x00\x00\x00\x02"
+	kvHeaderLen = 16
+	kvTombstone = 1 << 31
+	kvMaxKey    = 4 << 10
+	kvMaxValue  = kvTombstone - 1
+)
+
+var (
+	errKVClosed   = errors.New("archive is closed")
+	errKVReadOnly = errors.New("archive is opened read-only")
+)
+
+func (e kvEntry) size() int64 { return kvHeaderLen + int64(e.kn) + int64(e.vn) }
+
+// openKV membuka (atau membuat) store di path untuk dibaca dan ditulis. Bila
+// file rusak, salinannya disimpan dulu sebagai <path>.corrupt-<waktu>: ujung
+// yang terpotong (crash saat menulis) lalu dipangkas, sedangkan rekaman rusak
+// di tengah dilewati dan file ditulis ulang tanpa rekaman itu.
+func openKV(path string) (*kvStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	s := &kvStore{path: path, f: f, unlock: unlock, index: map[string]kvEntry{}}
+	if err := s.load(); err != nil {
+		f.Close()
+		unlock()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if err := s.repair(); err != nil {
+		if !s.closed {
+			s.f.Close()
+			s.unlock()
+		}
+		return nil, fmt.Errorf("repair %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// openKVReadOnly membuka store tanpa lock dan tanpa pernah menulis apa pun.
+// File yang belum ada dianggap kosong; bagian yang rusak hanya dilewati.
+func openKVReadOnly(path string) (*kvStore, error) {
+	s := &kvStore{path: path, readOnly: true, unlock: func() {}, index: map[string]kvEntry{}}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.f = f
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *kvStore) load() error {
+	st, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	total := st.Size()
+	if total == 0 {
+		if s.readOnly {
+			return nil
+		}
+		if _, err := s.f.WriteAt([]byte(kvMagic), 0); err != nil {
+			return err
+		}
+		s.size = int64(len(kvMagic))
+		return s.f.Sync()
+	}
+
+	magic := make([]byte, len(kvMagic))
+	if _, err := s.f.ReadAt(magic, 0); err != nil || string(magic) != kvMagic {
+		return errors.New("not an archive file")
+	}
+	off := int64(len(kvMagic))
+	for off < total {
+		e, key, tomb, ok := s.readRecord(off, total)
+		if !ok {
+			next := s.resync(off+1, total)
+			if next < 0 {
+				break // sisanya ujung yang terpotong
+			}
+			s.Damaged += next - off
+			off = next
+			continue
+		}
+		if old, ok := s.index[key]; ok {
+			s.stale += old.size()
+		}
+		if tomb {
+			delete(s.index, key)
+			s.stale += e.size()
+		} else {
+			s.index[key] = e
+		}
+		off += e.size()
+	}
+	s.size, s.torn = off, total-off
+	return nil
+}
+
+// decodeKVHeader mengurai header rekaman; ok false bila crc header tidak
+// cocok atau panjang key di luar batas.
+func decodeKVHeader(hdr []byte) (kn, vn uint32, tomb, ok bool) {
+	if crc32.ChecksumIEEE(hdr[4:kvHeaderLen]) != binary.LittleEndian.Uint32(hdr) {
+		return 0, 0, false, false
+	}
+	kn = binary.LittleEndian.Uint32(hdr[4:])
+	vraw := binary.LittleEndian.Uint32(hdr[8:])
+	return kn, vraw &^ kvTombstone, vraw&kvTombstone != 0, kn <= kvMaxKey
+}
+
+// readRecord membaca rekaman di off; ok false bila terpotong atau CRC-nya
+// tidak cocok.
+func (s *kvStore) readRecord(off, total int64) (e kvEntry, key string, tomb, ok bool) {
+	if off+kvHeaderLen > total {
+		return e, "", false, false
+	}
+	hdr := make([]byte, kvHeaderLen)
+	if _, err := s.f.ReadAt(hdr, off); err != nil {
+		return e, "", false, false
+	}
+	kn, vn, tomb, ok := decodeKVHeader(hdr)
+	e = kvEntry{off: off, kn: kn, vn: vn}
+	if !ok || off+e.size() > total {
+		return e, "", false, false
+	}
+	body := make([]byte, e.size()-kvHeaderLen)
+	if _, err := s.f.ReadAt(body, off+kvHeaderLen); err != nil {
+		return e, "", false, false
+	}
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(hdr[12:]) {
+		return e, "", false, false
+	}
+	return e, string(body[:e.kn]), tomb, true
+}
+
+// resync mencari awal rekaman valid berikutnya mulai dari off; -1 bila tidak
+// ada lagi. File dibaca per blok dan hanya posisi yang header-nya lolos
+// decodeKVHeader yang dibaca isinya.
+func (s *kvStore) resync(off, total int64) int64 {
+	buf := make([]byte, 64<<10)
+	for off+kvHeaderLen <= total {
+		n, _ := s.f.ReadAt(buf, off)
+		if n < kvHeaderLen {
+			return -1
+		}
+		for i := 0; i+kvHeaderLen <= n; i++ {
+			if _, _, _, ok := decodeKVHeader(buf[i : i+kvHeaderLen]); !ok {
+				continue
+			}
+			if _, _, _, ok := s.readRecord(off+int64(i), total); ok {
+				return off + int64(i)
+			}
+		}
+		off += int64(n - kvHeaderLen + 1)
+	}
+	return -1
+}
+
+// repair menyimpan salinan file yang rusak lalu membuang bagian rusaknya.
+func (s *kvStore) repair() error {
+	if s.Damaged == 0 && s.torn == 0 {
+		return nil
+	}
+	backup := fmt.Sprintf("%s.corrupt-%s", s.path, time.Now().Format("20060102-150405"))
+	if err := copyFile(s.path, backup); err != nil {
+		return fmt.Errorf("backup before repair: %w", err)
+	}
+	s.Backup = backup
+	if s.Damaged > 0 {
+		return s.Compact()
+	}
+	if err := s.f.Truncate(s.size); err != nil {
+		return err
+	}
+	s.torn = 0
+	return s.f.Sync()
+}
+
+// Get mengembalikan value untuk key; ok false bila key tidak ada.
+func (s *kvStore) Get(key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, false, errKVClosed
+	}
+	e, ok := s.index[key]
+	if !ok {
+		return nil, false, nil
+	}
+	v := make([]byte, e.vn)
+	if _, err := s.f.ReadAt(v, e.off+kvHeaderLen+int64(e.kn)); err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// Has melaporkan apakah key ada tanpa membaca value-nya.
+func (s *kvStore) Has(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.index[key]
+	return ok
+}
+
+func (s *kvStore) Put(key string, value []byte) error {
+	if len(key) > kvMaxKey {
+		return fmt.Errorf("key %.32s... is too long", key)
+	}
+	if len(value) > kvMaxValue {
+		return fmt.Errorf("value for %s is too large", key)
+	}
+	return s.append(key, value, false)
+}
+
+// Delete menulis tombstone; key yang tidak ada diabaikan.
+func (s *kvStore) Delete(key string) error {
+	if !s.Has(key) {
+		return nil
+	}
+	return s.append(key, nil, true)
+}
+
+func (s *kvStore) append(key string, value []byte, tomb bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	rec := encodeKVRecord(key, value, tomb)
+	if _, err := s.f.WriteAt(rec, s.size); err != nil {
+		// buang sisa tulisan setengah jadi agar rekaman berikutnya tetap
+		// terbaca
+		_ = s.f.Truncate(s.size)
+		return err
+	}
+	if old, ok := s.index[key]; ok {
+		s.stale += old.size()
+	}
+	e := kvEntry{off: s.size, kn: uint32(len(key)), vn: uint32(len(value))}
+	if tomb {
+		delete(s.index, key)
+		s.stale += e.size()
+	} else {
+		s.index[key] = e
+	}
+	s.size += e.size()
+	return nil
+}
+
+func encodeKVRecord(key string, value []byte, tomb bool) []byte {
+	rec := make([]byte, kvHeaderLen, kvHeaderLen+len(key)+len(value))
+	vraw := uint32(len(value))
+	if tomb {
+		vraw |= kvTombstone
+	}
+	binary.LittleEndian.PutUint32(rec[4:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(rec[8:], vraw)
+	rec = append(rec, key...)
+	rec = append(rec, value...)
+	binary.LittleEndian.PutUint32(rec[12:], crc32.ChecksumIEEE(rec[kvHeaderLen:]))
+	binary.LittleEndian.PutUint32(rec[0:], crc32.ChecksumIEEE(rec[4:kvHeaderLen]))
+	return rec
+}
+
+// Keys mengembalikan semua key berawalan prefix, terurut.
+func (s *kvStore) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []string
+	for k := range s.index {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Sync memastikan semua tulisan sudah sampai ke disk.
+func (s *kvStore) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *kvStore) writable() error {
+	switch {
+	case s.closed:
+		return errKVClosed
+	case s.readOnly:
+		return errKVReadOnly
+	}
+	return nil
+}
+
+// kvCompactMin: file baru ditulis ulang bila rekaman usang melebihi ini dan
+// separuh ukuran file.
+const kvCompactMin = 1 << 20
+
+// Compact menulis ulang file hanya dengan value terbaru tiap key.
+func (s *kvStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	dir, base := filepath.Split(s.path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	keys := make([]string, 0, len(s.index))
+	for k := range s.index {
+		keys = append(keys, k)
+	}
+	// urut offset agar file lama dibaca berurutan
+	sort.Slice(keys, func(i, j int) bool { return s.index[keys[i]].off < s.index[keys[j]].off })
+	w := bufio.NewWriter(tmp)
+	if _, err := w.WriteString(kvMagic); err != nil {
+		return fail(err)
+	}
+	index := make(map[string]kvEntry, len(keys))
+	off := int64(len(kvMagic))
+	for _, k := range keys {
+		e := s.index[k]
+		rec := make([]byte, e.size())
+		if _, err := s.f.ReadAt(rec, e.off); err != nil {
+			return fail(err)
+		}
+		if _, err := w.Write(rec); err != nil {
+			return fail(err)
+		}
+		e.off = off
+		index[k] = e
+		off += e.size()
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	// Windows tidak bisa mengganti file yang masih terbuka
+	s.f.Close()
+	renameErr := os.Rename(tmp.Name(), s.path)
+	if renameErr != nil {
+		_ = os.Remove(tmp.Name())
+	} else if d, err := os.Open(dir); err == nil {
+		// fsync direktori agar rename tahan crash (diabaikan di Windows)
+		_ = d.Sync()
+		d.Close()
+	}
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0600)
+	if err != nil {
+		s.f, s.closed = nil, true
+		s.unlock()
+		return errors.Join(renameErr, err)
+	}
+	s.f = f
+	if renameErr != nil {
+		return renameErr
+	}
+	s.index, s.size, s.torn, s.stale, s.Damaged = index, off, 0, 0, 0
+	return nil
+}
+
+// Close menyimpan ke disk, memadatkan file bila banyak rekaman usang, lalu
+// melepas lock.
+func (s *kvStore) Close() error {
+	if s.closed {
+		return nil
+	}
+	if s.readOnly {
+		s.closed = true
+		if s.f != nil {
+			return s.f.Close()
+		}
+		return nil
+	}
+	var err error
+	if s.stale > kvCompactMin && s.stale > s.size/2 {
+		err = s.Compact()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return err // Compact gagal membuka ulang file dan sudah melepas lock
+	}
+	if serr := s.f.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f, s.closed = nil, true
+	s.unlock()
+	return err
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestKV(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.db")
+	s, err := openKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		if err := s.Put(fmt.Sprintf("k%02d", i), bytes.Repeat([]byte{byte('a' + i)}, 50)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKVRoundTrip(t *testing.T) {
+	path := writeTestKV(t, 5)
+	s, err := openKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Delete("k01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("k02", []byte("baru")); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Keys("k"); len(got) != 4 {
+		t.Fatalf("Keys = %v", got)
+	}
+	if v, ok, _ := s.Get("k02"); !ok || string(v) != "baru" {
+		t.Fatalf("Get(k02) = %q, %v", v, ok)
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := s.Get("k04"); !ok || len(v) != 50 {
+		t.Fatalf("Get(k04) setelah Compact = %q, %v", v, ok)
+	}
+}
+
+func TestKVCorruptMiddle(t *testing.T) {
+	path := writeTestKV(t, 4)
+	orig, _ := os.ReadFile(path)
+	// rusak satu byte di value rekaman pertama
+	bad := bytes.Clone(orig)
+	bad[len(kvMagic)+kvHeaderLen+10] ^= 0xff
+	if err := os.WriteFile(path, bad, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := openKVReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ro.Keys(""); len(got) != 3 || ro.Damaged == 0 {
+		t.Fatalf("read-only: Keys = %v, Damaged = %d", got, ro.Damaged)
+	}
+	if err := ro.Put("x", nil); err != errKVReadOnly {
+		t.Fatalf("Put read-only = %v", err)
+	}
+	ro.Close()
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, bad) {
+		t.Fatal("read-only open changed the file")
+	}
+
+	rw, err := openKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rw.Keys(""); len(got) != 3 {
+		t.Fatalf("Keys = %v", got)
+	}
+	if backup, _ := os.ReadFile(rw.Backup); !bytes.Equal(backup, bad) {
+		t.Fatalf("backup %q does not hold the damaged file", rw.Backup)
+	}
+	rw.Close()
+
+	// setelah diperbaiki file utuh lagi
+	again, err := openKVReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	if again.Damaged != 0 || len(again.Keys("")) != 3 {
+		t.Fatalf("after repair: Damaged = %d, Keys = %v", again.Damaged, again.Keys(""))
+	}
+}
+
+func TestKVCorruptHeader(t *testing.T) {
+	path := writeTestKV(t, 4)
+	orig, _ := os.ReadFile(path)
+	// panjang value rekaman pertama diubah tetapi masih muat di file: tanpa
+	// crc header rekaman itu akan menelan rekaman sesudahnya
+	bad := bytes.Clone(orig)
+	binary.LittleEndian.PutUint32(bad[len(kvMagic)+8:], 100)
+	if err := os.WriteFile(path, bad, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := openKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.Keys(""); len(got) != 3 || got[0] != "k01" {
+		t.Fatalf("Keys = %v", got)
+	}
+	if err := s.Put(strings.Repeat("k", kvMaxKey+1), nil); err == nil {
+		t.Fatal("Put accepted an oversized key")
+	}
+}
+
+func TestDecodeKVHeader(t *testing.T) {
+	rec := encodeKVRecord("kunci", []byte("isi"), true)
+	if kn, vn, tomb, ok := decodeKVHeader(rec); !ok || kn != 5 || vn != 3 || !tomb {
+		t.Fatalf("decode = %d, %d, %v, %v", kn, vn, tomb, ok)
+	}
+	for i := range kvHeaderLen {
+		bad := bytes.Clone(rec)
+		bad[i] ^= 0x01
+		if _, _, _, ok := decodeKVHeader(bad); ok {
+			t.Fatalf("flipped header byte %d still decodes", i)
+		}
+	}
+	// crc header cocok tetapi panjang key tidak masuk akal
+	hdr := make([]byte, kvHeaderLen)
+	binary.LittleEndian.PutUint32(hdr[4:], kvMaxKey+1)
+	binary.LittleEndian.PutUint32(hdr, crc32.ChecksumIEEE(hdr[4:]))
+	if _, _, _, ok := decodeKVHeader(hdr); ok {
+		t.Fatal("oversized key length accepted")
+	}
+}
+
+func TestKVTornTail(t *testing.T) {
+	path := writeTestKV(t, 3)
+	orig, _ := os.ReadFile(path)
+	torn := append(bytes.Clone(orig), encodeKVRecord("k99", []byte("setengah"), false)[:15]...)
+	if err := os.WriteFile(path, torn, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := openKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Damaged != 0 || len(s.Keys("")) != 3 || s.Backup == "" {
+		t.Fatalf("Damaged = %d, Keys = %v, Backup = %q", s.Damaged, s.Keys(""), s.Backup)
+	}
+	if err := s.Put("k03", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	s, err = openKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, ok, _ := s.Get("k03"); !ok || string(v) != "x" {
+		t.Fatalf("Get(k03) = %q, %v", v, ok)
+	}
+}
+
+func TestKVReadOnlyMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tidak-ada", "archive.db")
+	s, err := openKVReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Keys("")) != 0 {
+		t.Fatal("expected empty store")
+	}
+	s.Close()
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Fatal("read-only open created files")
+	}
+}
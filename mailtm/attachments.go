@@ -0,0 +1,98 @@
+package mailtm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ========================= Lampiran =========================
+
+// Attachment adalah metadata lampiran; isinya diunduh lewat
+// DownloadAttachment.
+type Attachment struct {
+	ID               string `json:"id"`
+	Filename         string `json:"filename"`
+	ContentType      string `json:"contentType"`
+	Disposition      string `json:"disposition"`
+	TransferEncoding string `json:"transferEncoding"`
+	Related          bool   `json:"related"`
+	Size             int    `json:"size"`
+	DownloadURL      string `json:"downloadUrl"`
+}
+
+// DownloadAttachment mengunduh isi lampiran dengan token akun. Pemanggil wajib
+// menutup ReadCloser yang dikembalikan.
+func (c *Client) DownloadAttachment(ctx context.Context, a Attachment) (io.ReadCloser, error) {
+	p := a.DownloadURL
+	if strings.Contains(p, "://") {
+		// token hanya dikirim ke API kita sendiri
+		rel, ok := strings.CutPrefix(p, strings.TrimRight(c.BaseURL, "/"))
+		if !ok {
+			return nil, errors.New("attachment download url points to a different host")
+		}
+		p = rel
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, errors.New("attachment has no download url")
+	}
+	res, err := c.doAuth(ctx, "GET", p, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeResponse(res, nil); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// nama perangkat Windows yang tidak boleh dipakai sebagai nama file
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+const maxFilenameLen = 200
+
+// SafeFilename membuat nama lampiran aman disimpan di semua OS: komponen
+// path dan karakter terlarang dibuang, nama perangkat Windows diberi awalan,
+// dan panjangnya dibatasi. Nama kosong menjadi "attachment".
+func SafeFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = path.Base(strings.ToValidUTF8(name, ""))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "attachment"
+	}
+	stem, ext := name, path.Ext(name)
+	if ext != "" && len(ext) <= 16 {
+		stem = strings.TrimSuffix(name, ext)
+	} else {
+		ext = ""
+	}
+	if reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		stem = "_" + stem
+	}
+	// potong di batas rune agar tidak menghasilkan UTF-8 rusak
+	for len(stem)+len(ext) > maxFilenameLen {
+		_, n := utf8.DecodeLastRuneInString(stem)
+		stem = stem[:len(stem)-n]
+	}
+	if stem == "" {
+		stem = "attachment"
+	}
+	return stem + ext
+}
@@ -0,0 +1,382 @@
+// Package mailtm adalah klien untuk API email sementara Mail.tm
+// (https://docs.mail.tm): domain, akun, token, pesan dan notifikasi Mercure.
+package mailtm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ========================= Mail.tm Client =========================
+
+const DefaultBaseURL = "https://api.mail.tm"
+
+// HTTPDoer adalah bagian dari *http.Client yang dipakai Client; bisa diganti
+// untuk pengujian atau transport khusus.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client menyimpan kredensial satu akun. Field kredensial boleh diisi
+// langsung (mis. dari penyimpanan) sebelum memanggil method lain.
+type Client struct {
+	BaseURL    string
+	MercureURL string // kosong = selalu polling
+	Token      string
+	TokenExp   time.Time
+	AccountID  string
+	Address    string
+	Password   string
+
+	// OnToken dipanggil setiap kali token baru didapat, mis. untuk menyimpannya.
+	OnToken func(token string, exp time.Time)
+
+	http      HTTPDoer
+	timeout   time.Duration
+	userAgent string
+}
+
+type Option func(*Client)
+
+func WithBaseURL(u string) Option {
+	return func(c *Client) { c.BaseURL = strings.TrimRight(u, "/") }
+}
+
+// WithMercureURL mengganti hub Mercure; kosong mematikan SSE.
+func WithMercureURL(u string) Option {
+	return func(c *Client) { c.MercureURL = u }
+}
+
+// WithTimeout membatasi setiap request API (tidak berlaku untuk stream SSE).
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithHTTPClient mengganti HTTPDoer bawaan (http.Client dengan NewTransport).
+func WithHTTPClient(d HTTPDoer) Option {
+	return func(c *Client) { c.http = d }
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		BaseURL:    DefaultBaseURL,
+		MercureURL: DefaultMercureURL,
+		timeout:    30 * time.Second,
+		userAgent:  "mailtm-go",
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.http == nil {
+		c.http = defaultHTTP()
+	}
+	return c
+}
+
+// do mengirim request dengan User-Agent dan timeout Client. Timeout tetap
+// berlaku sampai body respons ditutup.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if c.timeout <= 0 {
+		return c.http.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	res, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		// API Platform hanya menerima PATCH sebagai JSON merge patch
+		ct := "application/json"
+		if method == http.MethodPatch {
+			ct = "application/merge-patch+json"
+		}
+		req.Header.Set("Content-Type", ct)
+	}
+	return req, nil
+}
+
+// sendJSON mengirim request tanpa autentikasi dan men-decode body 2xx ke out.
+func (c *Client) sendJSON(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res, out)
+}
+
+// decodeResponse mengubah status non-2xx menjadi *APIError.
+func decodeResponse(res *http.Response, out any) error {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newAPIError(res)
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// ========================= Domain & akun =========================
+
+type Domain struct {
+	ID        string `json:"id"`
+	Domain    string `json:"domain"`
+	IsActive  bool   `json:"isActive"`
+	IsPrivate bool   `json:"isPrivate"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Account adalah akun Mail.tm seperti yang dikembalikan API.
+type Account struct {
+	ID         string `json:"id"`
+	Address    string `json:"address"`
+	Quota      int    `json:"quota"`
+	Used       int    `json:"used"`
+	IsDisabled bool   `json:"isDisabled"`
+	IsDeleted  bool   `json:"isDeleted"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+// DomainRandom sebagai nama domain berarti "pilih domain aktif secara acak".
+const DomainRandom = "random"
+
+// PickDomain memilih domain dari daftar: kosong = domain aktif pertama,
+// "random" = domain aktif acak, selain itu harus ada dan aktif.
+func PickDomain(doms []Domain, want string) (string, error) {
+	var active []Domain
+	for _, d := range doms {
+		if d.IsActive {
+			active = append(active, d)
+		}
+	}
+	want = strings.TrimSpace(want)
+	switch {
+	case len(active) == 0:
+		return "", errors.New("no domains available")
+	case want == "":
+		return active[0].Domain, nil
+	case strings.EqualFold(want, DomainRandom):
+		return active[randIndex(len(active))].Domain, nil
+	}
+	for _, d := range doms {
+		if strings.EqualFold(d.Domain, want) {
+			if !d.IsActive {
+				return "", fmt.Errorf("domain %s is not active", d.Domain)
+			}
+			return d.Domain, nil
+		}
+	}
+	return "", fmt.Errorf("domain %s is not available", want)
+}
+
+type hydraDomains struct {
+	Members []Domain `json:"hydra:member"`
+}
+
+func (c *Client) GetDomains(ctx context.Context) ([]Domain, error) {
+	var hd hydraDomains
+	if err := c.sendJSON(ctx, "GET", "/domains", nil, &hd); err != nil {
+		return nil, err
+	}
+	return hd.Members, nil
+}
+
+// Register membuat akun address (username@domain) lalu login. Kredensial
+// disimpan di Client.
+func (c *Client) Register(ctx context.Context, address, password string) (*Account, error) {
+	if address == "" || password == "" {
+		return nil, errors.New("address and password are required")
+	}
+	var acc Account
+	in := map[string]string{"address": address, "password": password}
+	if err := c.sendJSON(ctx, "POST", "/accounts", in, &acc); err != nil {
+		return nil, err
+	}
+	c.Address = address
+	c.Password = password
+	c.AccountID = acc.ID
+	if _, err := c.GetToken(ctx); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// Me mengambil data akun yang sedang login.
+func (c *Client) Me(ctx context.Context) (*Account, error) {
+	res, err := c.doAuth(ctx, "GET", "/me", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var acc Account
+	if err := decodeResponse(res, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+func (c *Client) DeleteAccount(ctx context.Context) error {
+	if c.AccountID == "" || c.Token == "" {
+		return errors.New("no token/account id; please login first")
+	}
+	res, err := c.doAuth(ctx, "DELETE", "/accounts/"+c.AccountID, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := decodeResponse(res, nil); err != nil {
+		return err
+	}
+	c.Token = ""
+	c.TokenExp = time.Time{}
+	c.AccountID = ""
+	c.Address = ""
+	c.Password = ""
+	return nil
+}
+
+// ========================= Token =========================
+
+// GetToken login dengan Address/Password dan menyimpan token di Client.
+func (c *Client) GetToken(ctx context.Context) (string, error) {
+	var tk struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	in := map[string]string{"address": c.Address, "password": c.Password}
+	if err := c.sendJSON(ctx, "POST", "/token", in, &tk); err != nil {
+		return "", err
+	}
+	c.Token = tk.Token
+	c.TokenExp, _ = jwtExpiry(c.Token)
+	if c.AccountID == "" {
+		c.AccountID = tk.ID
+	}
+	if c.OnToken != nil {
+		c.OnToken(c.Token, c.TokenExp)
+	}
+	return c.Token, nil
+}
+
+// tokenSkew: token dianggap kedaluwarsa sedikit lebih awal dari klaim exp.
+const tokenSkew = time.Minute
+
+// jwtExpiry membaca klaim exp dari payload JWT tanpa memverifikasi tanda tangan.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("malformed jwt")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("jwt has no exp claim")
+	}
+	return time.Unix(int64(claims.Exp), 0), nil
+}
+
+// TokenValid melaporkan apakah token masih bisa dipakai tanpa login ulang.
+func (c *Client) TokenValid() bool {
+	if c.Token == "" {
+		return false
+	}
+	// tanpa exp yang terbaca, andalkan 401 untuk memicu login ulang
+	return c.TokenExp.IsZero() || time.Until(c.TokenExp) > tokenSkew
+}
+
+// ensureToken login ulang hanya jika token belum ada atau hampir kedaluwarsa.
+func (c *Client) ensureToken(ctx context.Context) error {
+	if c.TokenValid() || c.Password == "" {
+		return nil
+	}
+	_, err := c.GetToken(ctx)
+	return err
+}
+
+// doAuth mengirim request terautentikasi. Jika server membalas 401, token
+// diperbarui lalu request diulang sekali.
+func (c *Client) doAuth(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+	send := func() (*http.Response, error) {
+		if c.Token == "" {
+			return nil, errors.New("no token; please login first")
+		}
+		req, err := c.newRequest(ctx, method, path, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		return c.do(req)
+	}
+	res, err := send()
+	if err != nil || res.StatusCode != http.StatusUnauthorized || c.Password == "" {
+		return res, err
+	}
+	res.Body.Close()
+	if _, err := c.GetToken(ctx); err != nil {
+		return nil, err
+	}
+	return send()
+}
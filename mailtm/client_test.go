@@ -0,0 +1,266 @@
+package mailtm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+	"github.com/luzyver/Mail.TM-CLI/mailtm/mailtmtest"
+)
+
+// newClient memakai http.Client milik srv, tanpa rate limit bawaan yang
+// hanya diperlukan untuk API asli.
+func newClient(srv *httptest.Server) *mailtm.Client {
+	return mailtm.New(
+		mailtm.WithBaseURL(srv.URL),
+		mailtm.WithMercureURL(srv.URL+"/.well-known/mercure"),
+		mailtm.WithHTTPClient(srv.Client()),
+	)
+}
+
+// newFake menjalankan mailtmtest.Fake di httptest.Server dan mengembalikan
+// client yang sudah terdaftar sebagai address.
+func newFake(t *testing.T, address string) (*mailtmtest.Fake, *mailtm.Client) {
+	t.Helper()
+	fake := mailtmtest.NewFake()
+	srv := httptest.NewServer(fake.Handler())
+	t.Cleanup(srv.Close)
+	c := newClient(srv)
+	if _, err := c.Register(context.Background(), address, "password123"); err != nil {
+		t.Fatal(err)
+	}
+	return fake, c
+}
+
+func deliverN(t *testing.T, fake *mailtmtest.Fake, to string, n int) {
+	t.Helper()
+	for i := range n {
+		if _, err := fake.Deliver(to, mailtm.Message{Subject: fmt.Sprintf("pesan %d", i), Text: "isi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRegisterAndToken(t *testing.T) {
+	ctx := context.Background()
+	fake := mailtmtest.NewFake()
+	srv := httptest.NewServer(fake.Handler())
+	defer srv.Close()
+	c := newClient(srv)
+
+	doms, err := c.GetDomains(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain, err := mailtm.PickDomain(doms, "")
+	if err != nil || domain != mailtmtest.DefaultDomain {
+		t.Fatalf("PickDomain = %q, %v", domain, err)
+	}
+
+	var tokens int
+	c.OnToken = func(string, time.Time) { tokens++ }
+	address := "bob@" + domain
+	acc, err := c.Register(ctx, address, "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Address != address || c.AccountID != acc.ID || !c.TokenValid() || tokens != 1 {
+		t.Fatalf("Register: acc=%+v id=%q valid=%v tokens=%d", acc, c.AccountID, c.TokenValid(), tokens)
+	}
+	me, err := c.Me(ctx)
+	if err != nil || me.ID != acc.ID {
+		t.Fatalf("Me = %+v, %v", me, err)
+	}
+
+	// token yang ditolak server diperbarui lalu request diulang
+	c.Token = "bukan.token.valid"
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatalf("Me after bad token: %v", err)
+	}
+	if tokens != 2 {
+		t.Fatalf("tokens = %d, want 2", tokens)
+	}
+
+	if _, err := newClient(srv).Register(ctx, address, "password123"); !errors.Is(err, mailtm.ErrAddressTaken) {
+		t.Fatalf("second Register = %v, want ErrAddressTaken", err)
+	}
+	other := newClient(srv)
+	other.Address, other.Password = address, "salah-password"
+	if _, err := other.GetToken(ctx); !errors.Is(err, mailtm.ErrUnauthorized) {
+		t.Fatalf("GetToken with wrong password = %v, want ErrUnauthorized", err)
+	}
+
+	if err := c.DeleteAccount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetToken(ctx); !errors.Is(err, mailtm.ErrUnauthorized) {
+		t.Fatalf("GetToken after DeleteAccount = %v", err)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	ctx := context.Background()
+	const address = "pages@" + mailtmtest.DefaultDomain
+	fake, c := newFake(t, address)
+	deliverN(t, fake, address, 65)
+
+	p, err := c.GetMessagesPage(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Messages) != 30 || p.TotalItems != 65 || p.Next != 2 || p.Last != 3 {
+		t.Fatalf("page 1: %d messages, total %d, next %d, last %d", len(p.Messages), p.TotalItems, p.Next, p.Last)
+	}
+	if p, err = c.GetMessagesPage(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Messages) != 5 || p.Next != 0 {
+		t.Fatalf("page 3: %d messages, next %d", len(p.Messages), p.Next)
+	}
+
+	seen := map[string]bool{}
+	for m, err := range c.AllMessages(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[m.ID] {
+			t.Fatalf("duplicate message %s", m.ID)
+		}
+		seen[m.ID] = true
+	}
+	if len(seen) != 65 {
+		t.Fatalf("AllMessages returned %d messages, want 65", len(seen))
+	}
+}
+
+func TestDeleteAllMessages(t *testing.T) {
+	ctx := context.Background()
+	const address = "bersih@" + mailtmtest.DefaultDomain
+	fake, c := newFake(t, address)
+	deliverN(t, fake, address, 45)
+
+	n, err := c.DeleteAllMessages(ctx)
+	if err != nil || n != 45 {
+		t.Fatalf("DeleteAllMessages = %d, %v", n, err)
+	}
+	if left := fake.Messages(address); len(left) != 0 {
+		t.Fatalf("%d messages left on the server", len(left))
+	}
+	if msgs, err := c.GetMessages(ctx); err != nil || len(msgs) != 0 {
+		t.Fatalf("GetMessages = %d, %v", len(msgs), err)
+	}
+}
+
+func TestDeliverAttachment(t *testing.T) {
+	ctx := context.Background()
+	const address = "lampiran@" + mailtmtest.DefaultDomain
+	fake, c := newFake(t, address)
+	sent, err := fake.Deliver(address, mailtm.Message{Subject: "faktur"},
+		mailtmtest.File{Filename: "faktur.txt", ContentType: "text/plain", Data: []byte("total 10")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.GetMessage(ctx, sent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Filename != "faktur.txt" {
+		t.Fatalf("attachments = %+v", m.Attachments)
+	}
+	body, err := c.DownloadAttachment(ctx, m.Attachments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "total 10" {
+		t.Fatalf("attachment = %q", data)
+	}
+}
+
+func TestDeliverWaitForN(t *testing.T) {
+	ctx := context.Background()
+	const address = "tunggu@" + mailtmtest.DefaultDomain
+	fake, c := newFake(t, address)
+
+	go func() {
+		for _, from := range []string{"news@promo.test", "noreply@shop.test", "news@promo.test", "billing@shop.test"} {
+			time.Sleep(20 * time.Millisecond)
+			fake.Deliver(address, mailtm.Message{
+				From:    mailtm.EmailAddress{Address: from},
+				Subject: "Kode verifikasi",
+				Text:    "kode anda 482913",
+			})
+		}
+	}()
+	filter := mailtm.WaitFilter{From: "shop.test", Subject: regexp.MustCompile(`(?i)kode`)}
+	got, err := c.WaitForN(ctx, 2, 5*time.Second, 50*time.Millisecond, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].From.Address != "noreply@shop.test" || got[1].From.Address != "billing@shop.test" {
+		t.Fatalf("WaitForN = %+v", got)
+	}
+	if code, ok := mailtm.BestCode(&got[0]); !ok || code.Value != "482913" {
+		t.Fatalf("BestCode = %+v, %v", code, ok)
+	}
+}
+
+func TestWaitForNTimeout(t *testing.T) {
+	ctx := context.Background()
+	const address = "sepi@" + mailtmtest.DefaultDomain
+	fake, c := newFake(t, address)
+	deliverN(t, fake, address, 1)
+
+	got, err := c.WaitForN(ctx, 3, 300*time.Millisecond, 50*time.Millisecond, mailtm.WaitFilter{After: time.Now().Add(-time.Minute)})
+	var te *mailtm.TimeoutError
+	if !errors.As(err, &te) || !errors.Is(err, mailtm.ErrTimeout) {
+		t.Fatalf("err = %v, want *TimeoutError", err)
+	}
+	if len(got) != 1 || te.Got != 1 || te.Want != 3 {
+		t.Fatalf("got %d messages, TimeoutError %+v", len(got), te)
+	}
+
+	// pembatalan dari pemanggil bukan timeout
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.WaitForN(cctx, 1, time.Second, 50*time.Millisecond, mailtm.WaitFilter{}); errors.Is(err, mailtm.ErrTimeout) {
+		t.Fatalf("canceled WaitForN = %v", err)
+	}
+}
+
+// Request yang melewati batas waktu Client bukan timeout menunggu.
+func TestWaitForNRequestTimeout(t *testing.T) {
+	ctx := context.Background()
+	fake := mailtmtest.NewFake()
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/messages/") {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+		fake.Handler().ServeHTTP(w, r)
+	})
+	srv := httptest.NewServer(slow)
+	defer srv.Close()
+	c := mailtm.New(mailtm.WithBaseURL(srv.URL), mailtm.WithHTTPClient(srv.Client()), mailtm.WithTimeout(100*time.Millisecond))
+	c.MercureURL = ""
+	const address = "lambat@" + mailtmtest.DefaultDomain
+	if _, err := c.Register(ctx, address, "password123"); err != nil {
+		t.Fatal(err)
+	}
+	deliverN(t, fake, address, 1)
+
+	_, err := c.WaitForN(ctx, 1, 5*time.Second, 50*time.Millisecond, mailtm.WaitFilter{After: time.Now().Add(-time.Minute)})
+	if err == nil || errors.Is(err, mailtm.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a request deadline error", err)
+	}
+}
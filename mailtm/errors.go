@@ -0,0 +1,118 @@
+package mailtm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ========================= Error API =========================
+
+// Sentinel untuk errors.Is; *APIError cocok dengan sentinel sesuai status
+// HTTP dan isi violations-nya.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	ErrAddressTaken = errors.New("address already taken")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+type Violation struct {
+	PropertyPath string `json:"propertyPath"`
+	Message      string `json:"message"`
+	Code         string `json:"code"`
+}
+
+// APIError adalah balasan non-2xx dari Mail.tm. Pakai errors.Is dengan
+// sentinel Err* untuk membedakan penyebabnya.
+type APIError struct {
+	Status      int
+	Method      string
+	Endpoint    string // path tanpa query, mis. /messages/123
+	Title       string
+	Description string
+	Violations  []Violation
+	Body        string // body mentah bila bukan JSON yang dikenal
+}
+
+func (e *APIError) Error() string {
+	msg := e.Description
+	if msg == "" && len(e.Violations) > 0 {
+		parts := make([]string, len(e.Violations))
+		for i, v := range e.Violations {
+			parts[i] = v.PropertyPath + ": " + v.Message
+		}
+		msg = strings.Join(parts, "; ")
+	}
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = strings.TrimSpace(e.Body)
+	}
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Endpoint, e.Status, msg)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	case ErrAddressTaken:
+		if e.Status != http.StatusUnprocessableEntity {
+			return false
+		}
+		for _, v := range e.Violations {
+			if v.PropertyPath == "address" && strings.Contains(strings.ToLower(v.Message), "already used") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newAPIError membaca body respons (hydra, problem+json, atau {code,message})
+// menjadi *APIError. Body respons tidak ditutup di sini.
+func newAPIError(res *http.Response) error {
+	e := &APIError{Status: res.StatusCode}
+	if res.Request != nil {
+		e.Method = res.Request.Method
+		e.Endpoint = res.Request.URL.Path
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	var parsed struct {
+		HydraTitle       string      `json:"hydra:title"`
+		HydraDescription string      `json:"hydra:description"`
+		Title            string      `json:"title"`
+		Detail           string      `json:"detail"`
+		Message          string      `json:"message"`
+		Violations       []Violation `json:"violations"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		e.Title = firstNonEmpty(parsed.HydraTitle, parsed.Title)
+		e.Description = firstNonEmpty(parsed.HydraDescription, parsed.Detail, parsed.Message)
+		e.Violations = parsed.Violations
+	}
+	if e.Title == "" && e.Description == "" && len(e.Violations) == 0 {
+		e.Body = string(body)
+	}
+	return e
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
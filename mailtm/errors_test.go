@@ -0,0 +1,106 @@
+package mailtm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func apiErrorFor(status int, body string) *APIError {
+	res := &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    httptest.NewRequest(http.MethodPost, "/accounts?page=2", nil),
+	}
+	return newAPIError(res).(*APIError)
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	taken := `{"@type":"ConstraintViolationList","violations":[{"propertyPath":"address","message":"This value is already used."}]}`
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error // nil berarti tidak cocok dengan sentinel mana pun
+	}{
+		{"unauthorized", 401, `{"code":401,"message":"JWT Token not found"}`, ErrUnauthorized},
+		{"not found", 404, `{"hydra:title":"An error occurred","hydra:description":"Not Found"}`, ErrNotFound},
+		{"rate limited", 429, "", ErrRateLimited},
+		{"address taken", 422, taken, ErrAddressTaken},
+		{"violation lain", 422, `{"violations":[{"propertyPath":"password","message":"This value is too short."}]}`, nil},
+		{"already used bukan 422", 400, taken, nil},
+		{"server error", 500, "oops", nil},
+	}
+	sentinels := []error{ErrUnauthorized, ErrNotFound, ErrRateLimited, ErrAddressTaken}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// dibungkus seperti di pemanggil
+			err := fmt.Errorf("create: %w", apiErrorFor(tt.status, tt.body))
+			for _, s := range sentinels {
+				if got := errors.Is(err, s); got != (s == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAPIErrorBodies(t *testing.T) {
+	tests := []struct {
+		name, body              string
+		title, description, msg string
+		violations              int
+		raw                     bool
+	}{
+		{
+			name:        "hydra",
+			body:        `{"@context":"/contexts/Error","@type":"hydra:Error","hydra:title":"An error occurred","hydra:description":"Invalid credentials."}`,
+			title:       "An error occurred",
+			description: "Invalid credentials.",
+			msg:         "POST /accounts: 400 Invalid credentials.",
+		},
+		{
+			name:        "problem+json",
+			body:        `{"type":"https://tools.ietf.org/html/rfc2616#section-10","title":"An error occurred","detail":"Domain not found","status":400}`,
+			title:       "An error occurred",
+			description: "Domain not found",
+			msg:         "POST /accounts: 400 Domain not found",
+		},
+		{
+			name:       "violations",
+			body:       `{"violations":[{"propertyPath":"address","message":"bad"},{"propertyPath":"password","message":"short"}]}`,
+			violations: 2,
+			msg:        "POST /accounts: 400 address: bad; password: short",
+		},
+		{
+			name:        "code message",
+			body:        `{"code":400,"message":"Bad thing"}`,
+			description: "Bad thing",
+			msg:         "POST /accounts: 400 Bad thing",
+		},
+		{name: "kosong", body: "", raw: true, msg: "POST /accounts: 400 Bad Request"},
+		{name: "bukan JSON", body: "<html>bad gateway</html>\n", raw: true, msg: "POST /accounts: 400 <html>bad gateway</html>"},
+		{name: "JSON tanpa field dikenal", body: `{"foo":1}`, raw: true, msg: `POST /accounts: 400 {"foo":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := apiErrorFor(400, tt.body)
+			if e.Status != 400 || e.Method != http.MethodPost || e.Endpoint != "/accounts" {
+				t.Fatalf("got %+v", e)
+			}
+			if e.Title != tt.title || e.Description != tt.description || len(e.Violations) != tt.violations {
+				t.Fatalf("title %q, description %q, violations %v", e.Title, e.Description, e.Violations)
+			}
+			if (e.Body != "") != (tt.raw && tt.body != "") {
+				t.Fatalf("Body = %q", e.Body)
+			}
+			if e.Error() != tt.msg {
+				t.Fatalf("Error() = %q, want %q", e.Error(), tt.msg)
+			}
+		})
+	}
+}
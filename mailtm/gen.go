@@ -0,0 +1,210 @@
+package mailtm
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ========================= Generator username & password =========================
+
+const (
+	charsetLower   = "abcdefghijklmnopqrstuvwxyz"
+	charsetUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	charsetDigits  = "0123456789"
+	charsetSymbols = "!@#$%^&*"
+	charsetAmbig   = "0Oo1lI" // mudah tertukar saat dibaca atau diketik ulang
+
+	usernameCharset = charsetLower + charsetDigits
+)
+
+// randIndex mengembalikan bilangan acak seragam di [0, n) dari crypto/rand.
+func randIndex(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// crypto/rand tidak gagal di platform yang didukung
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+// randomString membuat string sepanjang n dari charset.
+func randomString(n int, charset string) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(charset[randIndex(len(charset))])
+	}
+	return b.String()
+}
+
+// randomLocalPart seperti randomString, tetapi titik tidak diambil di awal,
+// di akhir, atau tepat sesudah titik lain karena local-part seperti itu
+// ditolak. charset harus memuat karakter selain titik.
+func randomLocalPart(n int, charset string) string {
+	noDot := strings.ReplaceAll(charset, ".", "")
+	b := make([]byte, n)
+	for i := range b {
+		cs := charset
+		if i == 0 || i == n-1 || b[i-1] == '.' {
+			cs = noDot
+		}
+		b[i] = cs[randIndex(len(cs))]
+	}
+	return string(b)
+}
+
+func shuffle(b []byte) {
+	for i := len(b) - 1; i > 0; i-- {
+		j := randIndex(i + 1)
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+func stripChars(s, remove string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(remove, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// PasswordPolicy mengatur bentuk password acak yang dibuat Generate.
+type PasswordPolicy struct {
+	Length int
+	// MinClasses: jumlah kelas karakter (kecil, besar, angka, simbol) yang
+	// wajib muncul, diambil berurutan dari kelas pertama.
+	MinClasses       int
+	ExcludeAmbiguous bool
+}
+
+// DefaultPasswordPolicy: 16 karakter dari keempat kelas, tanpa karakter ambigu.
+var DefaultPasswordPolicy = PasswordPolicy{Length: 16, MinClasses: 4, ExcludeAmbiguous: true}
+
+func (p PasswordPolicy) Generate() (string, error) {
+	classes := []string{charsetLower, charsetUpper, charsetDigits, charsetSymbols}
+	if p.ExcludeAmbiguous {
+		for i := range classes {
+			classes[i] = stripChars(classes[i], charsetAmbig)
+		}
+	}
+	if p.MinClasses < 1 || p.MinClasses > len(classes) {
+		return "", fmt.Errorf("password classes must be between 1 and %d", len(classes))
+	}
+	if p.Length < p.MinClasses || p.Length < 6 {
+		return "", fmt.Errorf("password length must be at least %d", max(p.MinClasses, 6))
+	}
+	all := strings.Join(classes, "")
+	b := make([]byte, 0, p.Length)
+	for _, cls := range classes[:p.MinClasses] {
+		b = append(b, cls[randIndex(len(cls))])
+	}
+	for len(b) < p.Length {
+		b = append(b, all[randIndex(len(all))])
+	}
+	shuffle(b)
+	return string(b), nil
+}
+
+// ========================= Gaya username =========================
+
+const (
+	UsernameRandom        = "random"
+	UsernamePronounceable = "pronounceable"
+	UsernameWords         = "words"
+)
+
+var UsernameStyles = []string{UsernameRandom, UsernamePronounceable, UsernameWords}
+
+var (
+	consonants = "bcdfghjklmnprstvz"
+	vowels     = "aeiou"
+)
+
+// daftar kata pendek untuk gaya "words"; hanya huruf kecil ASCII
+var usernameWords = strings.Fields(`
+	amber apple arrow aspen atlas autumn bamboo basil beacon birch blaze bloom
+	breeze brook cactus canyon cedar cherry cinder citrus clover cobalt comet
+	coral cosmos crane crystal cypress dawn delta desert dune eagle echo ember
+	falcon fern fjord flint forest fox frost galaxy garnet glacier granite
+	harbor hazel heron horizon indigo iris island ivory jade jasper juniper
+	kestrel lagoon lantern lark laurel lemon lilac linden lotus lunar maple
+	marble meadow mesa mint misty moss nectar nova oak ocean olive onyx orbit
+	orchid otter panda pebble pepper pine planet plum polar prairie quartz
+	quill rain raven reef ridge river robin ruby saffron sage sapphire shadow
+	sierra silver sky slate sparrow spruce star stone storm summit sunny swift
+	thistle thunder tide tiger topaz tulip valley velvet violet willow winter
+	wren zephyr
+`)
+
+// karakter yang boleh dipakai di charset username kustom
+const usernameAllowed = charsetLower + charsetDigits + "._-"
+
+const (
+	maxUsernameLength = 64 // batas local-part RFC 5321
+	// pronounceable: minimal satu pasang konsonan-vokal + 2 angka
+	minPronounceableLength = 4
+)
+
+// UsernameOptions mengatur bentuk username acak yang dibuat Generate.
+type UsernameOptions struct {
+	Style  string
+	Length int // random dan pronounceable; 0 = 10
+	// Charset hanya untuk gaya random; kosong = huruf kecil dan angka.
+	Charset string
+}
+
+// GenerateUsername membuat local-part alamat email. length hanya berlaku
+// untuk gaya random dan pronounceable.
+func GenerateUsername(style string, length int) (string, error) {
+	return UsernameOptions{Style: style, Length: length}.Generate()
+}
+
+func (o UsernameOptions) Generate() (string, error) {
+	length := o.Length
+	if length <= 0 {
+		length = 10
+	}
+	if length > maxUsernameLength {
+		return "", fmt.Errorf("username length must be at most %d", maxUsernameLength)
+	}
+	if o.Charset != "" && o.Style != "" && o.Style != UsernameRandom {
+		return "", fmt.Errorf("username charset only applies to the %s style", UsernameRandom)
+	}
+	switch o.Style {
+	case "", UsernameRandom:
+		charset := usernameCharset
+		if o.Charset != "" {
+			for _, r := range o.Charset {
+				if !strings.ContainsRune(usernameAllowed, r) {
+					return "", fmt.Errorf("username charset may only contain %q, got %q", usernameAllowed, r)
+				}
+			}
+			if strings.Trim(o.Charset, ".") == "" {
+				return "", errors.New("username charset needs a character other than '.'")
+			}
+			charset = o.Charset
+		}
+		return randomLocalPart(length, charset), nil
+	case UsernamePronounceable:
+		if length < minPronounceableLength {
+			return "", fmt.Errorf("pronounceable username length must be at least %d", minPronounceableLength)
+		}
+		b := make([]byte, length-2)
+		for i := range b {
+			if i%2 == 0 {
+				b[i] = consonants[randIndex(len(consonants))]
+			} else {
+				b[i] = vowels[randIndex(len(vowels))]
+			}
+		}
+		return string(b) + randomString(2, charsetDigits), nil
+	case UsernameWords:
+		w1 := usernameWords[randIndex(len(usernameWords))]
+		w2 := usernameWords[randIndex(len(usernameWords))]
+		return w1 + w2 + randomString(3, charsetDigits), nil
+	}
+	return "", errors.New("unknown username style " + o.Style + " (" + strings.Join(UsernameStyles, ", ") + ")")
+}
@@ -0,0 +1,64 @@
+package mailtm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateUsernameLength(t *testing.T) {
+	for _, style := range []string{UsernameRandom, UsernamePronounceable} {
+		for length := minPronounceableLength; length <= 20; length++ {
+			u, err := GenerateUsername(style, length)
+			if err != nil {
+				t.Fatalf("%s/%d: %v", style, length, err)
+			}
+			if len(u) != length {
+				t.Fatalf("%s/%d: got %q (%d chars)", style, length, u, len(u))
+			}
+		}
+	}
+}
+
+func TestGenerateUsernamePronounceableTooShort(t *testing.T) {
+	for _, length := range []int{1, 2, 3} {
+		if u, err := GenerateUsername(UsernamePronounceable, length); err == nil {
+			t.Fatalf("length %d: expected error, got %q", length, u)
+		}
+	}
+}
+
+func TestUsernameCharset(t *testing.T) {
+	u, err := UsernameOptions{Length: 32, Charset: "ab"}.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Trim(u, "ab") != "" || len(u) != 32 {
+		t.Fatalf("got %q", u)
+	}
+	for _, o := range []UsernameOptions{
+		{Charset: "AB"},
+		{Charset: "a@b"},
+		{Charset: "."},
+		{Charset: ".."},
+		{Style: UsernameWords, Charset: "ab"},
+		{Length: maxUsernameLength + 1},
+	} {
+		if _, err := o.Generate(); err == nil {
+			t.Fatalf("%+v: expected error", o)
+		}
+	}
+}
+
+func TestUsernameCharsetDots(t *testing.T) {
+	for _, length := range []int{1, 2, 3, 10, maxUsernameLength} {
+		for range 200 {
+			u, err := UsernameOptions{Length: length, Charset: ".a"}.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(u) != length || strings.HasPrefix(u, ".") || strings.HasSuffix(u, ".") || strings.Contains(u, "..") {
+				t.Fatalf("length %d: got invalid local part %q", length, u)
+			}
+		}
+	}
+}
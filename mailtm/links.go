@@ -0,0 +1,144 @@
+package mailtm
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ========================= Tautan =========================
+
+// Link adalah tautan di pesan. URL sudah dibuka dari redirect pelacak;
+// Original berisi href aslinya bila berbeda.
+type Link struct {
+	URL      string
+	Text     string
+	Original string
+}
+
+var (
+	anchorRe  = regexp.MustCompile(`(?is)<a\b[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))[^>]*>(.*?)</a>`)
+	bareURLRe = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+)
+
+// parameter yang dipakai layanan pelacak/redirect untuk membawa URL tujuan
+var redirectParams = []string{"url", "u", "q", "target", "dest", "destination", "redirect", "redirect_url", "link", "to", "r"}
+
+// Hanya path yang memang redirector yang dibuka, agar magic link seperti
+// /login?token=...&redirect=https://... tidak kehilangan token-nya.
+var redirectPaths = map[string]bool{
+	"url": true, "l": true, "l.php": true, "redirect": true, "redir": true, "click": true,
+	"track": true, "out": true, "away": true, "away.php": true, "link": true, "go": true,
+}
+
+// ExtractLinks mengambil tautan http(s) dari HTML dan teks pesan, tanpa
+// duplikat dan sesuai urutan kemunculan.
+func ExtractLinks(m *Message) []Link {
+	var out []Link
+	seen := map[string]bool{}
+	add := func(raw, text string) {
+		raw = strings.TrimSpace(html.UnescapeString(raw))
+		if !strings.HasPrefix(strings.ToLower(raw), "http://") && !strings.HasPrefix(strings.ToLower(raw), "https://") {
+			return // mailto:, tel:, anchor, dll.
+		}
+		u := UnwrapURL(raw)
+		if seen[u] {
+			return
+		}
+		seen[u] = true
+		l := Link{URL: u, Text: text}
+		if u != raw {
+			l.Original = raw
+		}
+		out = append(out, l)
+	}
+	body := m.HTML.String()
+	for _, a := range anchorRe.FindAllStringSubmatch(body, -1) {
+		add(a[1]+a[2]+a[3], strings.Join(strings.Fields(HTMLToText(a[4])), " "))
+	}
+	for _, raw := range bareURLRe.FindAllString(HTMLToText(body)+"\n"+m.Text, -1) {
+		add(trimURLPunct(raw), "")
+	}
+	return out
+}
+
+// trimURLPunct membuang tanda baca penutup kalimat yang ikut tertangkap.
+func trimURLPunct(u string) string {
+	for {
+		trimmed := strings.TrimRight(u, ".,;:!?")
+		// kurung tutup hanya dibuang jika tidak berpasangan
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == u {
+			return u
+		}
+		u = trimmed
+	}
+}
+
+// UnwrapURL membuka redirect pelacak yang umum (Google, Facebook, Outlook
+// SafeLinks, Proofpoint, parameter url=/u=/q= dll.) sampai ke URL tujuan.
+func UnwrapURL(raw string) string {
+	for range 5 {
+		next := unwrapOnce(raw)
+		if next == "" || next == raw {
+			return raw
+		}
+		raw = next
+	}
+	return raw
+}
+
+func unwrapOnce(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	// Proofpoint URL Defense v3: https://urldefense.com/v3/__<url>__;...
+	if strings.HasSuffix(host, "urldefense.com") && strings.HasPrefix(u.Path, "/v3/__") {
+		// diambil dari raw: query URL dalam ikut terpisah oleh url.Parse
+		_, rest, _ := strings.Cut(raw, "/v3/__")
+		inner, _, _ := strings.Cut(rest, "__")
+		if s, err := url.PathUnescape(inner); err == nil && isHTTPURL(s) {
+			return s
+		}
+		return ""
+	}
+	q := u.Query()
+	// Proofpoint v2: ?u=https-3A__example.com_path
+	if strings.HasSuffix(host, "urldefense.proofpoint.com") && q.Get("u") != "" {
+		s := strings.NewReplacer("-", "%", "_", "/").Replace(q.Get("u"))
+		if s, err := url.PathUnescape(s); err == nil && isHTTPURL(s) {
+			return s
+		}
+		return ""
+	}
+	seg := strings.ToLower(u.Path[strings.LastIndex(u.Path, "/")+1:])
+	if !redirectPaths[seg] && !strings.HasSuffix(host, "safelinks.protection.outlook.com") {
+		return ""
+	}
+	for _, p := range redirectParams {
+		if v := q.Get(p); isHTTPURL(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// MatchHost melaporkan apakah host URL sama dengan host atau subdomainnya.
+func MatchHost(rawURL, host string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	h, want := strings.ToLower(u.Hostname()), strings.ToLower(strings.TrimPrefix(host, "."))
+	return h == want || strings.HasSuffix(h, "."+want)
+}
@@ -0,0 +1,81 @@
+package mailtm
+
+import "testing"
+
+func TestUnwrapURL(t *testing.T) {
+	tests := []struct{ name, in, want string }{
+		{"google", "https://www.google.com/url?q=https://example.com/x%3Fa%3D1&sa=D&ust=1", "https://example.com/x?a=1"},
+		{"safelinks", "https://nam12.safelinks.protection.outlook.com/?url=https%3A%2F%2Fexample.com%2Fverify%3Ft%3Dabc&data=05%7C01&reserved=0", "https://example.com/verify?t=abc"},
+		{"proofpoint v2", "https://urldefense.proofpoint.com/v2/url?u=https-3A__example.com_path_a-3Fb-3D1&d=DwMF&c=x", "https://example.com/path/a?b=1"},
+		{"proofpoint v3", "https://urldefense.com/v3/__https://example.com/confirm?id=7__;!!AbC$", "https://example.com/confirm?id=7"},
+		{"berlapis", "https://www.google.com/url?q=" + "https%3A%2F%2Fnam12.safelinks.protection.outlook.com%2F%3Furl%3Dhttps%253A%252F%252Fexample.com%252Fz", "https://example.com/z"},
+		{"magic link tetap utuh", "https://app.example.com/login?token=abc123&redirect=https://app.example.com/home", "https://app.example.com/login?token=abc123&redirect=https://app.example.com/home"},
+		{"redirect bukan http", "https://example.com/redirect?url=javascript:alert(1)", "https://example.com/redirect?url=javascript:alert(1)"},
+		{"tanpa redirect", "https://example.com/a?b=c", "https://example.com/a?b=c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnwrapURL(tt.in); got != tt.want {
+				t.Fatalf("UnwrapURL(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	m := &Message{
+		HTML: HTMLParts{`<p><a href="https://www.google.com/url?q=https://example.com/a&amp;sa=D">Buka <b>akun</b></a>
+			<a href='mailto:x@example.com'>surat</a> <a href=https://example.com/a>dobel</a></p>`},
+		Text: "Lihat https://example.com/b. Atau (https://example.com/c), dan https://en.wikipedia.org/wiki/Go_(language)!",
+	}
+	got := ExtractLinks(m)
+	want := []Link{
+		{URL: "https://example.com/a", Text: "Buka akun", Original: "https://www.google.com/url?q=https://example.com/a&sa=D"},
+		{URL: "https://example.com/b"},
+		{URL: "https://example.com/c"},
+		{URL: "https://en.wikipedia.org/wiki/Go_(language)"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d links: %+v", len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("link %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTrimURLPunct(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://example.com/a.":           "https://example.com/a",
+		"https://example.com/a?!":          "https://example.com/a",
+		"https://example.com/a).":          "https://example.com/a",
+		"https://example.com/f(x)":         "https://example.com/f(x)",
+		"https://example.com/f(x)),":       "https://example.com/f(x)",
+		"https://example.com/path/file.go": "https://example.com/path/file.go",
+	} {
+		if got := trimURLPunct(in); got != want {
+			t.Errorf("trimURLPunct(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		url, host string
+		want      bool
+	}{
+		{"https://example.com/x", "example.com", true},
+		{"https://login.Example.com/x", "example.com", true},
+		{"https://example.com:8443/x", ".example.com", true},
+		{"https://badexample.com/x", "example.com", false},
+		{"https://example.com.evil.test/x", "example.com", false},
+		{"https://evil.test/?u=example.com", "example.com", false},
+		{"::", "example.com", false},
+	}
+	for _, tt := range tests {
+		if got := MatchHost(tt.url, tt.host); got != tt.want {
+			t.Errorf("MatchHost(%q, %q) = %v, want %v", tt.url, tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,740 @@
+// Package mailtmtest menyediakan server Mail.tm palsu di dalam proses untuk
+// pengujian dan demo tanpa API asli.
+//
+//	srv := mailtmtest.NewServer()
+//	defer srv.Close()
+//	c := mailtm.New(mailtm.WithBaseURL(srv.URL), mailtm.WithMercureURL(srv.MercureURL()))
+//	...
+//	srv.Deliver("user@"+mailtmtest.DefaultDomain, mailtm.Message{Subject: "Halo", Text: "kode 123456"})
+package mailtmtest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+const (
+	DefaultDomain = "example.test"
+	pageSize      = 30 // sama dengan API asli
+	accountQuota  = 40000000
+	mercurePath   = "/.well-known/mercure"
+)
+
+type account struct {
+	mailtm.Account
+	password string
+	messages []*mailtm.Message // terbaru dulu
+	files    map[string][]byte // "messageID/attachmentID" -> isi lampiran
+}
+
+// File adalah lampiran yang ikut dikirim lewat Deliver.
+type File struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Fake adalah state server palsu; aman dipakai dari banyak goroutine.
+type Fake struct {
+	// TokenTTL adalah umur token JWT yang diterbitkan (bawaan 1 jam).
+	TokenTTL time.Duration
+
+	mu        sync.Mutex
+	secret    []byte
+	domains   []mailtm.Domain
+	accounts  map[string]*account // per ID
+	byAddress map[string]*account
+	subs      map[string][]chan []byte // accountID -> stream SSE
+	eventSeq  int
+}
+
+func NewFake(domains ...string) *Fake {
+	if len(domains) == 0 {
+		domains = []string{DefaultDomain}
+	}
+	f := &Fake{
+		TokenTTL:  time.Hour,
+		secret:    randomBytes(32),
+		accounts:  map[string]*account{},
+		byAddress: map[string]*account{},
+		subs:      map[string][]chan []byte{},
+	}
+	for _, d := range domains {
+		f.AddDomain(d, true)
+	}
+	return f
+}
+
+// AddDomain menambah domain; domain nonaktif ditolak saat registrasi.
+func (f *Fake) AddDomain(name string, active bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.domains = append(f.domains, mailtm.Domain{
+		ID:        newID(),
+		Domain:    name,
+		IsActive:  active,
+		CreatedAt: now(),
+	})
+}
+
+// Deliver memasukkan pesan ke kotak masuk to, seolah baru diterima. Field
+// yang kosong (ID, tanggal, from, intro, ukuran) diisi otomatis; files
+// menjadi lampiran yang bisa diunduh.
+func (f *Fake) Deliver(to string, m mailtm.Message, files ...File) (*mailtm.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	acc := f.byAddress[strings.ToLower(to)]
+	if acc == nil {
+		return nil, fmt.Errorf("mailtmtest: no account %s", to)
+	}
+	msg := m
+	if msg.ID == "" {
+		msg.ID = newID()
+	}
+	msg.AccountID = acc.ID
+	if msg.CreatedAt == "" {
+		msg.CreatedAt = now()
+	}
+	if msg.From.Address == "" {
+		msg.From = mailtm.EmailAddress{Address: "sender@example.org", Name: "Sender"}
+	}
+	if len(msg.To) == 0 {
+		msg.To = []mailtm.EmailAddress{{Address: acc.Address}}
+	}
+	if msg.Intro == "" {
+		msg.Intro = intro(msg.Text)
+	}
+	msg.Attachments = nil
+	for i, file := range files {
+		a := mailtm.Attachment{
+			ID:               fmt.Sprintf("ATTACH%06d", i+1),
+			Filename:         file.Filename,
+			ContentType:      file.ContentType,
+			Disposition:      "attachment",
+			TransferEncoding: "base64",
+			Size:             len(file.Data),
+		}
+		if a.ContentType == "" {
+			a.ContentType = "application/octet-stream"
+		}
+		a.DownloadURL = "/messages/" + msg.ID + "/attachment/" + a.ID
+		msg.Attachments = append(msg.Attachments, a)
+		if acc.files == nil {
+			acc.files = map[string][]byte{}
+		}
+		acc.files[msg.ID+"/"+a.ID] = slices.Clone(file.Data)
+	}
+	msg.HasAttachments = len(msg.Attachments) > 0
+	if msg.Size == 0 {
+		msg.Size = len(msg.Text) + len(msg.HTML.String()) + len(msg.Subject)
+		for _, file := range files {
+			msg.Size += len(file.Data)
+		}
+	}
+	acc.messages = slices.Insert(acc.messages, 0, &msg)
+	acc.Used += msg.Size
+
+	f.eventSeq++
+	ev, _ := json.Marshal(struct {
+		Type string `json:"@type"`
+		mailtm.Message
+	}{"Message", summary(&msg)})
+	frame := []byte(fmt.Sprintf("id: %d\ndata: %s\n\n", f.eventSeq, ev))
+	for _, ch := range f.subs[acc.ID] {
+		select {
+		case ch <- frame:
+		default: // pelanggan lambat; ia akan mengejar lewat polling
+		}
+	}
+	out := msg
+	return &out, nil
+}
+
+// Messages mengembalikan salinan isi kotak masuk address, terbaru dulu.
+func (f *Fake) Messages(address string) []mailtm.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	acc := f.byAddress[strings.ToLower(address)]
+	if acc == nil {
+		return nil
+	}
+	out := make([]mailtm.Message, len(acc.messages))
+	for i, m := range acc.messages {
+		out[i] = *m
+	}
+	return out
+}
+
+// Handler mengembalikan http.Handler yang meniru API Mail.tm dan hub
+// Mercure di /.well-known/mercure.
+func (f *Fake) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /domains", f.listDomains)
+	mux.HandleFunc("GET /domains/{id}", f.getDomain)
+	mux.HandleFunc("POST /accounts", f.createAccount)
+	mux.HandleFunc("POST /token", f.token)
+	mux.HandleFunc("GET /me", f.auth(f.me))
+	mux.HandleFunc("GET /accounts/{id}", f.auth(f.getAccount))
+	mux.HandleFunc("DELETE /accounts/{id}", f.auth(f.deleteAccount))
+	mux.HandleFunc("GET /messages", f.auth(f.listMessages))
+	mux.HandleFunc("GET /messages/{id}", f.auth(f.getMessage))
+	mux.HandleFunc("DELETE /messages/{id}", f.auth(f.deleteMessage))
+	mux.HandleFunc("PATCH /messages/{id}", f.auth(f.patchMessage))
+	mux.HandleFunc("GET /messages/{id}/attachment/{attachment}", f.auth(f.getAttachment))
+	mux.HandleFunc("GET /messages/{id}/download", f.auth(f.downloadMessage))
+	mux.HandleFunc("GET /sources/{id}", f.auth(f.getSource))
+	mux.HandleFunc("GET "+mercurePath, f.auth(f.mercure))
+	mux.HandleFunc("POST /_fake/deliver", f.deliver)
+	return mux
+}
+
+// Server adalah Fake yang berjalan di httptest.Server.
+type Server struct {
+	*Fake
+	*httptest.Server
+}
+
+func NewServer(domains ...string) *Server {
+	f := NewFake(domains...)
+	return &Server{Fake: f, Server: httptest.NewServer(f.Handler())}
+}
+
+func (s *Server) MercureURL() string { return s.URL + mercurePath }
+
+// ========================= Handler =========================
+
+type hydraCollection struct {
+	Context    string     `json:"@context"`
+	ID         string     `json:"@id"`
+	Type       string     `json:"@type"`
+	Members    any        `json:"hydra:member"`
+	TotalItems int        `json:"hydra:totalItems"`
+	View       *hydraView `json:"hydra:view,omitempty"`
+}
+
+type hydraView struct {
+	ID    string `json:"@id"`
+	Type  string `json:"@type"`
+	First string `json:"hydra:first"`
+	Last  string `json:"hydra:last"`
+	Next  string `json:"hydra:next,omitempty"`
+	Prev  string `json:"hydra:previous,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func hydraError(w http.ResponseWriter, status int, desc string, violations ...mailtm.Violation) {
+	writeJSON(w, status, map[string]any{
+		"@context":          "/contexts/Error",
+		"@type":             "hydra:Error",
+		"hydra:title":       "An error occurred",
+		"hydra:description": desc,
+		"violations":        violations,
+	})
+}
+
+// jwtError meniru balasan 401 dari lapisan autentikasi API asli.
+func jwtError(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": 401, "message": msg})
+}
+
+func (f *Fake) listDomains(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	doms := slices.Clone(f.domains)
+	f.mu.Unlock()
+	writeJSON(w, http.StatusOK, hydraCollection{
+		Context: "/contexts/Domain", ID: "/domains", Type: "hydra:Collection",
+		Members: doms, TotalItems: len(doms),
+	})
+}
+
+func (f *Fake) getDomain(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.domains {
+		if d.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, d)
+			return
+		}
+	}
+	hydraError(w, http.StatusNotFound, "Not Found")
+}
+
+type credentials struct {
+	Address  string `json:"address"`
+	Password string `json:"password"`
+}
+
+func (f *Fake) createAccount(w http.ResponseWriter, r *http.Request) {
+	var in credentials
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		hydraError(w, http.StatusBadRequest, "Syntax error")
+		return
+	}
+	in.Address = strings.ToLower(strings.TrimSpace(in.Address))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var vs []mailtm.Violation
+	_, domain, ok := strings.Cut(in.Address, "@")
+	if _, err := mail.ParseAddress(in.Address); err != nil || !ok {
+		vs = append(vs, mailtm.Violation{PropertyPath: "address", Message: "This value is not a valid email address."})
+	} else if !slices.ContainsFunc(f.domains, func(d mailtm.Domain) bool { return d.IsActive && d.Domain == domain }) {
+		vs = append(vs, mailtm.Violation{PropertyPath: "address", Message: "The domain \"" + domain + "\" is not valid."})
+	} else if f.byAddress[in.Address] != nil {
+		vs = append(vs, mailtm.Violation{PropertyPath: "address", Message: "This value is already used."})
+	}
+	if len(in.Password) < 6 {
+		vs = append(vs, mailtm.Violation{PropertyPath: "password", Message: "This value is too short. It should have 6 characters or more."})
+	}
+	if len(vs) > 0 {
+		parts := make([]string, len(vs))
+		for i, v := range vs {
+			parts[i] = v.PropertyPath + ": " + v.Message
+		}
+		hydraError(w, http.StatusUnprocessableEntity, strings.Join(parts, "\n"), vs...)
+		return
+	}
+	acc := &account{
+		Account: mailtm.Account{
+			ID:        newID(),
+			Address:   in.Address,
+			Quota:     accountQuota,
+			CreatedAt: now(),
+			UpdatedAt: now(),
+		},
+		password: in.Password,
+	}
+	f.accounts[acc.ID] = acc
+	f.byAddress[acc.Address] = acc
+	writeJSON(w, http.StatusCreated, acc.Account)
+}
+
+func (f *Fake) token(w http.ResponseWriter, r *http.Request) {
+	var in credentials
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		hydraError(w, http.StatusBadRequest, "Syntax error")
+		return
+	}
+	f.mu.Lock()
+	acc := f.byAddress[strings.ToLower(in.Address)]
+	f.mu.Unlock()
+	if acc == nil || !hmac.Equal([]byte(acc.password), []byte(in.Password)) {
+		jwtError(w, "Invalid credentials.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": acc.ID, "token": f.sign(acc)})
+}
+
+// auth memeriksa Bearer JWT dan meneruskan akun pemiliknya ke h.
+func (f *Fake) auth(h func(http.ResponseWriter, *http.Request, *account)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || tok == "" {
+			jwtError(w, "JWT Token not found")
+			return
+		}
+		id, problem := f.verify(tok)
+		if problem != "" {
+			jwtError(w, problem)
+			return
+		}
+		f.mu.Lock()
+		acc := f.accounts[id]
+		f.mu.Unlock()
+		if acc == nil {
+			jwtError(w, "Invalid JWT Token")
+			return
+		}
+		h(w, r, acc)
+	}
+}
+
+func (f *Fake) me(w http.ResponseWriter, r *http.Request, acc *account) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writeJSON(w, http.StatusOK, acc.Account)
+}
+
+func (f *Fake) getAccount(w http.ResponseWriter, r *http.Request, acc *account) {
+	if r.PathValue("id") != acc.ID {
+		hydraError(w, http.StatusForbidden, "Access Denied.")
+		return
+	}
+	f.me(w, r, acc)
+}
+
+func (f *Fake) deleteAccount(w http.ResponseWriter, r *http.Request, acc *account) {
+	if r.PathValue("id") != acc.ID {
+		hydraError(w, http.StatusForbidden, "Access Denied.")
+		return
+	}
+	f.mu.Lock()
+	delete(f.accounts, acc.ID)
+	delete(f.byAddress, acc.Address)
+	for _, ch := range f.subs[acc.ID] {
+		close(ch)
+	}
+	delete(f.subs, acc.ID)
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Fake) listMessages(w http.ResponseWriter, r *http.Request, acc *account) {
+	page := 1
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			hydraError(w, http.StatusBadRequest, "Page should not be less than 1")
+			return
+		}
+		page = n
+	}
+	f.mu.Lock()
+	total := len(acc.messages)
+	lo, hi := min((page-1)*pageSize, total), min(page*pageSize, total)
+	items := make([]mailtm.Message, 0, hi-lo)
+	for _, m := range acc.messages[lo:hi] {
+		items = append(items, summary(m))
+	}
+	f.mu.Unlock()
+
+	last := max((total+pageSize-1)/pageSize, 1)
+	col := hydraCollection{
+		Context: "/contexts/Message", ID: "/messages", Type: "hydra:Collection",
+		Members: items, TotalItems: total,
+	}
+	if total > pageSize {
+		pageIRI := func(n int) string { return "/messages?page=" + strconv.Itoa(n) }
+		col.View = &hydraView{ID: pageIRI(page), Type: "hydra:PartialCollectionView", First: pageIRI(1), Last: pageIRI(last)}
+		if page < last {
+			col.View.Next = pageIRI(page + 1)
+		}
+		if page > 1 {
+			col.View.Prev = pageIRI(page - 1)
+		}
+	}
+	writeJSON(w, http.StatusOK, col)
+}
+
+// findMessage harus dipanggil dengan f.mu terkunci.
+func (f *Fake) findMessage(acc *account, id string) (int, *mailtm.Message) {
+	for i, m := range acc.messages {
+		if m.ID == id {
+			return i, m
+		}
+	}
+	return -1, nil
+}
+
+func (f *Fake) getMessage(w http.ResponseWriter, r *http.Request, acc *account) {
+	f.mu.Lock()
+	_, m := f.findMessage(acc, r.PathValue("id"))
+	var out mailtm.Message
+	if m != nil {
+		out = *m
+	}
+	f.mu.Unlock()
+	if m == nil {
+		hydraError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (f *Fake) patchMessage(w http.ResponseWriter, r *http.Request, acc *account) {
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/merge-patch+json" {
+		hydraError(w, http.StatusUnsupportedMediaType, `The content-type "`+mt+`" is not supported.`)
+		return
+	}
+	var in struct {
+		Seen *bool `json:"seen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		hydraError(w, http.StatusBadRequest, "Syntax error")
+		return
+	}
+	f.mu.Lock()
+	_, m := f.findMessage(acc, r.PathValue("id"))
+	if m != nil && in.Seen != nil {
+		m.Seen = *in.Seen
+	}
+	var out mailtm.Message
+	if m != nil {
+		out = *m
+	}
+	f.mu.Unlock()
+	if m == nil {
+		hydraError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (f *Fake) deleteMessage(w http.ResponseWriter, r *http.Request, acc *account) {
+	f.mu.Lock()
+	i, m := f.findMessage(acc, r.PathValue("id"))
+	if m != nil {
+		acc.messages = slices.Delete(acc.messages, i, i+1)
+		acc.Used -= m.Size
+		for _, a := range m.Attachments {
+			delete(acc.files, m.ID+"/"+a.ID)
+		}
+	}
+	f.mu.Unlock()
+	if m == nil {
+		hydraError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Fake) getAttachment(w http.ResponseWriter, r *http.Request, acc *account) {
+	f.mu.Lock()
+	_, m := f.findMessage(acc, r.PathValue("id"))
+	var att *mailtm.Attachment
+	var data []byte
+	if m != nil {
+		if i := slices.IndexFunc(m.Attachments, func(a mailtm.Attachment) bool { return a.ID == r.PathValue("attachment") }); i >= 0 {
+			att = &m.Attachments[i]
+			data = acc.files[m.ID+"/"+att.ID]
+		}
+	}
+	f.mu.Unlock()
+	if att == nil {
+		hydraError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// source mengembalikan sumber mentah pesan id, atau nil jika tidak ada.
+func (f *Fake) source(acc *account, id string) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, m := f.findMessage(acc, id); m != nil {
+		return rawSource(m, acc.files)
+	}
+	return nil
+}
+
+func (f *Fake) getSource(w http.ResponseWriter, r *http.Request, acc *account) {
+	id := r.PathValue("id")
+	raw := f.source(acc, id)
+	if raw == nil {
+		hydraError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"@context":    "/contexts/Source",
+		"@id":         "/sources/" + id,
+		"@type":       "Source",
+		"id":          id,
+		"downloadUrl": "/messages/" + id + "/download",
+		"data":        string(raw),
+	})
+}
+
+func (f *Fake) downloadMessage(w http.ResponseWriter, r *http.Request, acc *account) {
+	raw := f.source(acc, r.PathValue("id"))
+	if raw == nil {
+		hydraError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	w.Header().Set("Content-Type", "message/rfc822")
+	w.Header().Set("Content-Length", strconv.Itoa(len(raw)))
+	_, _ = w.Write(raw)
+}
+
+// mercure mengirim event Message untuk topik /accounts/{id} milik token.
+func (f *Fake) mercure(w http.ResponseWriter, r *http.Request, acc *account) {
+	if r.URL.Query().Get("topic") != "/accounts/"+acc.ID {
+		hydraError(w, http.StatusForbidden, "Access Denied.")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		hydraError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	ch := make(chan []byte, 16)
+	f.mu.Lock()
+	f.subs[acc.ID] = append(f.subs[acc.ID], ch)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.subs[acc.ID] = slices.DeleteFunc(f.subs[acc.ID], func(c chan []byte) bool { return c == ch })
+		f.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ":\n\n")
+	flusher.Flush()
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame, ok := <-ch:
+			if !ok {
+				return // akun dihapus
+			}
+			_, _ = w.Write(frame)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ":\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+type deliverRequest struct {
+	To          string `json:"to"`
+	From        string `json:"from"`
+	Subject     string `json:"subject"`
+	Text        string `json:"text"`
+	HTML        string `json:"html"`
+	Attachments []struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"contentType"`
+		Content     []byte `json:"content"` // base64
+	} `json:"attachments"`
+}
+
+// deliver adalah endpoint tambahan (bukan API Mail.tm) untuk memasukkan pesan
+// dari luar proses, mis. lewat curl saat demo.
+func (f *Fake) deliver(w http.ResponseWriter, r *http.Request) {
+	var in deliverRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		hydraError(w, http.StatusBadRequest, "Syntax error")
+		return
+	}
+	m := mailtm.Message{Subject: in.Subject, Text: in.Text}
+	if in.From != "" {
+		m.From = mailtm.EmailAddress{Address: in.From}
+	}
+	if in.HTML != "" {
+		m.HTML = mailtm.HTMLParts{in.HTML}
+	}
+	var files []File
+	for _, a := range in.Attachments {
+		files = append(files, File{Filename: a.Filename, ContentType: a.ContentType, Data: a.Content})
+	}
+	msg, err := f.Deliver(in.To, m, files...)
+	if err != nil {
+		hydraError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, msg)
+}
+
+// ========================= JWT =========================
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// sign menerbitkan JWT HS256 dengan klaim id akun, seperti API asli.
+func (f *Fake) sign(acc *account) string {
+	iat := time.Now()
+	header := b64([]byte(`{"typ":"JWT","alg":"HS256"}`))
+	claims, _ := json.Marshal(map[string]any{
+		"iat":      iat.Unix(),
+		"exp":      iat.Add(f.TokenTTL).Unix(),
+		"id":       acc.ID,
+		"username": acc.Address,
+	})
+	signing := header + "." + b64(claims)
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(signing))
+	return signing + "." + b64(mac.Sum(nil))
+}
+
+// verify memeriksa tanda tangan dan exp, lalu mengembalikan id akun. problem
+// berisi pesan 401 seperti API asli jika token ditolak.
+func (f *Fake) verify(tok string) (id, problem string) {
+	const invalid = "Invalid JWT Token"
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return "", invalid
+	}
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", invalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", invalid
+	}
+	var claims struct {
+		Exp int64  `json:"exp"`
+		ID  string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return "", invalid
+	}
+	if time.Now().Unix() >= claims.Exp {
+		return "", "Expired JWT Token"
+	}
+	return claims.ID, ""
+}
+
+// ========================= Utils =========================
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newID membuat ID heksadesimal 24 karakter seperti ObjectId di API asli.
+func newID() string { return hex.EncodeToString(randomBytes(12)) }
+
+func now() string { return time.Now().UTC().Format(time.RFC3339) }
+
+func intro(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > 120 {
+		return string(r[:120]) + "…"
+	}
+	return text
+}
+
+// summary adalah bentuk pesan di daftar /messages: tanpa isi text dan html.
+func summary(m *mailtm.Message) mailtm.Message {
+	s := *m
+	s.Text = ""
+	s.HTML = nil
+	s.Attachments = nil
+	return s
+}
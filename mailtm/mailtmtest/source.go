@@ -0,0 +1,124 @@
+package mailtmtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+// ========================= Sumber RFC 5322 =========================
+
+// mimeNode adalah satu bagian MIME yang siap ditulis.
+type mimeNode struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func textNode(mediaType, s string) mimeNode {
+	var b bytes.Buffer
+	qp := quotedprintable.NewWriter(&b)
+	_, _ = io.WriteString(qp, s)
+	qp.Close()
+	return mimeNode{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"})},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		body: b.Bytes(),
+	}
+}
+
+func fileNode(a mailtm.Attachment, data []byte) mimeNode {
+	enc := base64.StdEncoding.EncodeToString(data)
+	var b bytes.Buffer
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc)
+	return mimeNode{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(a.ContentType, map[string]string{"name": a.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType(a.Disposition, map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		},
+		body: b.Bytes(),
+	}
+}
+
+func multipartNode(subtype string, children []mimeNode) mimeNode {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for _, c := range children {
+		pw, _ := mw.CreatePart(c.header)
+		_, _ = pw.Write(c.body)
+	}
+	mw.Close()
+	return mimeNode{
+		header: textproto.MIMEHeader{
+			"Content-Type": {mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": mw.Boundary()})},
+		},
+		body: b.Bytes(),
+	}
+}
+
+// rawSource menyusun sumber RFC 5322 dari pesan palsu: text/html sebagai
+// multipart/alternative dan lampiran dalam multipart/mixed, seperti surel
+// sungguhan. Harus dipanggil dengan f.mu terkunci.
+func rawSource(m *mailtm.Message, files map[string][]byte) []byte {
+	var alts []mimeNode
+	if m.Text != "" || len(m.HTML) == 0 {
+		alts = append(alts, textNode("text/plain", m.Text))
+	}
+	if len(m.HTML) > 0 {
+		alts = append(alts, textNode("text/html", m.HTML.String()))
+	}
+	root := alts[0]
+	if len(alts) > 1 {
+		root = multipartNode("alternative", alts)
+	}
+	if len(m.Attachments) > 0 {
+		parts := []mimeNode{root}
+		for _, a := range m.Attachments {
+			parts = append(parts, fileNode(a, files[m.ID+"/"+a.ID]))
+		}
+		root = multipartNode("mixed", parts)
+	}
+
+	addrs := make([]string, len(m.To))
+	for i, a := range m.To {
+		addrs[i] = (&mail.Address{Name: a.Name, Address: a.Address}).String()
+	}
+	date := time.Now()
+	if t, err := time.Parse(time.RFC3339, m.CreatedAt); err == nil {
+		date = t
+	}
+
+	var b bytes.Buffer
+	hdr := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	hdr("Return-Path", "<"+m.From.Address+">")
+	hdr("From", (&mail.Address{Name: m.From.Name, Address: m.From.Address}).String())
+	hdr("To", strings.Join(addrs, ", "))
+	hdr("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	hdr("Date", date.Format(time.RFC1123Z))
+	hdr("Message-ID", "<"+m.ID+"@mailtmtest>")
+	hdr("MIME-Version", "1.0")
+	for _, k := range []string{"Content-Type", "Content-Disposition", "Content-Transfer-Encoding"} {
+		if v := root.header.Get(k); v != "" {
+			hdr(k, v)
+		}
+	}
+	b.WriteString("\r\n")
+	b.Write(root.body)
+	return b.Bytes()
+}
@@ -0,0 +1,350 @@
+package mailtm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================= Pesan =========================
+
+type EmailAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+type Message struct {
+	ID             string         `json:"id"`
+	AccountID      string         `json:"accountId"`
+	From           EmailAddress   `json:"from"`
+	To             []EmailAddress `json:"to"`
+	Subject        string         `json:"subject"`
+	Intro          string         `json:"intro"`
+	CreatedAt      string         `json:"createdAt"`
+	HTML           HTMLParts      `json:"html"`
+	Text           string         `json:"text"`
+	Seen           bool           `json:"seen"`
+	Size           int            `json:"size"`
+	HasAttachments bool           `json:"hasAttachments"`
+	// Attachments hanya terisi pada GetMessage, tidak pada daftar pesan.
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// HTMLParts menampung field html yang dikirim API sebagai string atau
+// array string.
+type HTMLParts []string
+
+func (h *HTMLParts) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s == "" {
+			*h = nil
+		} else {
+			*h = HTMLParts{s}
+		}
+		return nil
+	}
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	*h = arr
+	return nil
+}
+
+// String menggabungkan semua bagian HTML.
+func (h HTMLParts) String() string { return strings.Join(h, "\n") }
+
+type hydraView struct {
+	Next string `json:"hydra:next"`
+	Last string `json:"hydra:last"`
+}
+
+type hydraMessages struct {
+	Members    []Message `json:"hydra:member"`
+	TotalItems int       `json:"hydra:totalItems"`
+	View       hydraView `json:"hydra:view"`
+}
+
+type MessagePage struct {
+	Messages   []Message
+	Page       int
+	TotalItems int
+	Next       int // 0 jika ini halaman terakhir
+	Last       int
+}
+
+// pageParam mengambil nilai ?page= dari IRI hydra:view; 0 jika tidak ada.
+func pageParam(iri string) int {
+	if iri == "" {
+		return 0
+	}
+	u, err := url.Parse(iri)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(u.Query().Get("page"))
+	return n
+}
+
+// GetMessages hanya mengambil halaman pertama (pesan terbaru). Pakai
+// GetMessagesPage atau AllMessages untuk seluruh kotak masuk.
+func (c *Client) GetMessages(ctx context.Context) ([]Message, error) {
+	p, err := c.GetMessagesPage(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return p.Messages, nil
+}
+
+func (c *Client) GetMessagesPage(ctx context.Context, page int) (*MessagePage, error) {
+	if page < 1 {
+		page = 1
+	}
+	res, err := c.doAuth(ctx, "GET", "/messages?page="+strconv.Itoa(page), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var hm hydraMessages
+	if err := decodeResponse(res, &hm); err != nil {
+		return nil, err
+	}
+	// mail.tm mengurutkan terbaru dulu; pastikan saja
+	p := &MessagePage{
+		Messages:   hm.Members,
+		Page:       page,
+		TotalItems: hm.TotalItems,
+		Next:       pageParam(hm.View.Next),
+		Last:       pageParam(hm.View.Last),
+	}
+	if p.Last == 0 {
+		p.Last = page
+	}
+	return p, nil
+}
+
+// AllMessages mengiterasi semua pesan di semua halaman, terbaru dulu.
+func (c *Client) AllMessages(ctx context.Context) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for page := 1; page > 0; {
+			p, err := c.GetMessagesPage(ctx, page)
+			if err != nil {
+				yield(Message{}, err)
+				return
+			}
+			for _, m := range p.Messages {
+				if !yield(m, nil) {
+					return
+				}
+			}
+			if len(p.Messages) == 0 || p.Next <= page {
+				return
+			}
+			page = p.Next
+		}
+	}
+}
+
+// DeleteAllMessages mengumpulkan semua ID dulu agar halaman tidak bergeser
+// saat pesan dihapus.
+func (c *Client) DeleteAllMessages(ctx context.Context) (int, error) {
+	var ids []string
+	for m, err := range c.AllMessages(ctx) {
+		if err != nil {
+			return 0, err
+		}
+		ids = append(ids, m.ID)
+	}
+	cnt := 0
+	var errs []error
+	for _, id := range ids {
+		if err := c.DeleteMessage(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		cnt++
+	}
+	return cnt, errors.Join(errs...)
+}
+
+func (c *Client) GetMessage(ctx context.Context, id string) (*Message, error) {
+	res, err := c.doAuth(ctx, "GET", "/messages/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var m Message
+	if err := decodeResponse(res, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (c *Client) DeleteMessage(ctx context.Context, id string) error {
+	res, err := c.doAuth(ctx, "DELETE", "/messages/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res, nil)
+}
+
+// MarkSeen menandai pesan sudah (seen=true) atau belum dibaca.
+func (c *Client) MarkSeen(ctx context.Context, id string, seen bool) error {
+	body, err := json.Marshal(map[string]bool{"seen": seen})
+	if err != nil {
+		return err
+	}
+	res, err := c.doAuth(ctx, "PATCH", "/messages/"+url.PathEscape(id), body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res, nil)
+}
+
+// MarkAllSeen menandai semua pesan yang statusnya berbeda dari seen dan
+// mengembalikan jumlah yang diubah.
+func (c *Client) MarkAllSeen(ctx context.Context, seen bool) (int, error) {
+	var ids []string
+	for m, err := range c.AllMessages(ctx) {
+		if err != nil {
+			return 0, err
+		}
+		if m.Seen != seen {
+			ids = append(ids, m.ID)
+		}
+	}
+	cnt := 0
+	var errs []error
+	for _, id := range ids {
+		if err := c.MarkSeen(ctx, id, seen); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		cnt++
+	}
+	return cnt, errors.Join(errs...)
+}
+
+// ========================= Menunggu pesan =========================
+
+// ErrTimeout cocok (errors.Is) dengan *TimeoutError.
+var ErrTimeout = errors.New("timed out waiting for messages")
+
+// TimeoutError dikembalikan WaitForMessage dan WaitForN bila batas waktu
+// habis sebelum cukup pesan yang cocok diterima.
+type TimeoutError struct {
+	Timeout time.Duration
+	Want    int
+	Got     int
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Want <= 1 {
+		return fmt.Sprintf("no matching message received within %s", e.Timeout)
+	}
+	return fmt.Sprintf("received %d of %d matching messages within %s", e.Got, e.Want, e.Timeout)
+}
+
+func (e *TimeoutError) Is(target error) bool { return target == ErrTimeout }
+
+// WaitFilter memilih pesan yang ditunggu; field kosong tidak menyaring.
+type WaitFilter struct {
+	From    string         // bagian dari alamat pengirim, tanpa beda huruf besar/kecil
+	Subject *regexp.Regexp // dicocokkan dengan subjek
+	Body    string         // harus muncul di teks atau HTML
+	// After juga membuat pesan yang sudah ada di kotak masuk ikut diperiksa
+	// bila diterima setelah waktu ini, agar pesan yang tiba sebelum menunggu
+	// dimulai tidak terlewat.
+	After time.Time
+	Match func(*Message) bool // predikat tambahan atas pesan lengkap
+}
+
+// matchSummary memeriksa field yang tersedia di daftar pesan, sehingga pesan
+// yang jelas tidak cocok tidak perlu diambil lengkap.
+func (f WaitFilter) matchSummary(m *Message) bool {
+	if f.From != "" && !strings.Contains(strings.ToLower(m.From.Address), strings.ToLower(f.From)) {
+		return false
+	}
+	if f.Subject != nil && !f.Subject.MatchString(m.Subject) {
+		return false
+	}
+	return f.After.IsZero() || receivedAfter(m, f.After)
+}
+
+// Matches melaporkan apakah pesan lengkap m lolos semua kriteria.
+func (f WaitFilter) Matches(m *Message) bool {
+	if !f.matchSummary(m) {
+		return false
+	}
+	if f.Body != "" && !strings.Contains(m.Text, f.Body) && !strings.Contains(m.HTML.String(), f.Body) {
+		return false
+	}
+	return f.Match == nil || f.Match(m)
+}
+
+// createdAt API hanya berpresisi detik, jadi pesan di detik yang sama
+// dengan t ikut dihitung.
+func receivedAfter(m *Message, t time.Time) bool {
+	at, err := time.Parse(time.RFC3339, m.CreatedAt)
+	return err == nil && !at.Before(t.Truncate(time.Second))
+}
+
+// WaitForMessage menunggu satu pesan baru yang cocok dengan filter dan
+// mengembalikannya lengkap (dengan isi). Bila timeout habis, error-nya
+// *TimeoutError.
+func (c *Client) WaitForMessage(ctx context.Context, timeout, interval time.Duration, filter WaitFilter) (*Message, error) {
+	got, err := c.WaitForN(ctx, 1, timeout, interval, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &got[0], nil
+}
+
+// WaitForN menunggu sampai n pesan yang cocok diterima, urut dari yang
+// paling awal tiba. Saat timeout, pesan yang sudah diterima tetap
+// dikembalikan bersama *TimeoutError.
+func (c *Client) WaitForN(ctx context.Context, n int, timeout, interval time.Duration, filter WaitFilter) ([]Message, error) {
+	n = max(n, 1)
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var got []Message
+	err := c.watchMessages(wctx, interval, filter.After, func(m Message) error {
+		if !filter.matchSummary(&m) {
+			return nil
+		}
+		det, err := c.GetMessage(wctx, m.ID)
+		if errors.Is(err, ErrNotFound) {
+			return nil // sudah dihapus sebelum sempat dibaca
+		}
+		if err != nil {
+			return err
+		}
+		if !filter.Matches(det) {
+			return nil
+		}
+		got = append(got, *det)
+		if len(got) >= n {
+			return ErrStopWatch
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrStopWatch):
+		return got, nil
+	// hanya batas waktu menunggu ini; deadline per request (WithTimeout)
+	// juga membungkus context.DeadlineExceeded tetapi bukan timeout
+	case wctx.Err() == context.DeadlineExceeded && ctx.Err() == nil:
+		return got, &TimeoutError{Timeout: timeout, Want: n, Got: len(got)}
+	}
+	return got, err
+}
@@ -0,0 +1,262 @@
+package mailtm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ========================= Sumber & MIME =========================
+
+// Source adalah sumber mentah RFC 5322 sebuah pesan dari /sources/{id}.
+type Source struct {
+	ID          string `json:"id"`
+	DownloadURL string `json:"downloadUrl"`
+	Data        string `json:"data"`
+}
+
+func (c *Client) GetSource(ctx context.Context, id string) (*Source, error) {
+	res, err := c.doAuth(ctx, "GET", "/sources/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var s Source
+	if err := decodeResponse(res, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// HeaderField adalah satu header dalam urutan aslinya, nilai sudah didekode
+// dari encoded-word RFC 2047.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// Part adalah satu simpul di pohon MIME.
+type Part struct {
+	// Path adalah nomor bagian bertingkat ("1", "1.2"); kosong untuk root
+	// multipart.
+	Path        string
+	Header      textproto.MIMEHeader
+	ContentType string
+	Params      map[string]string
+	Encoding    string // Content-Transfer-Encoding, huruf kecil
+	Disposition string
+	Filename    string
+	Body        []byte // isi yang sudah didekode; nil untuk multipart
+	Parts       []*Part
+}
+
+// ParsedMessage adalah hasil ParseMIME.
+type ParsedMessage struct {
+	Headers []HeaderField
+	Root    *Part
+}
+
+const maxMIMEDepth = 32
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: func(charset string, r io.Reader) (io.Reader, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := decodeCharset(charset, b)
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return strings.NewReader(s), nil
+}}
+
+// ParseMIME mengurai sumber RFC 5322 menjadi daftar header dan pohon bagian,
+// dengan isi quoted-printable/base64 sudah didekode. Bila ada bagian yang
+// rusak, hasil sebagian tetap dikembalikan bersama error-nya.
+func ParseMIME(r io.Reader) (*ParsedMessage, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	pm := &ParsedMessage{Headers: orderedHeaders(raw)}
+	pm.Root, err = parsePart(textproto.MIMEHeader(msg.Header), msg.Body, "", 0)
+	return pm, err
+}
+
+// orderedHeaders membaca blok header apa adanya, karena mail.Header adalah
+// map dan kehilangan urutan serta header duplikat.
+func orderedHeaders(raw []byte) []HeaderField {
+	var out []HeaderField
+	for line := range bytes.Lines(raw) {
+		l := strings.TrimRight(string(line), "\r\n")
+		if l == "" {
+			break
+		}
+		if (l[0] == ' ' || l[0] == '\t') && len(out) > 0 {
+			out[len(out)-1].Value += " " + strings.TrimSpace(l)
+			continue
+		}
+		name, value, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		out = append(out, HeaderField{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	for i := range out {
+		if v, err := wordDecoder.DecodeHeader(out[i].Value); err == nil {
+			out[i].Value = v
+		}
+	}
+	return out
+}
+
+func parsePart(h textproto.MIMEHeader, body io.Reader, path string, depth int) (*Part, error) {
+	p := &Part{
+		Path:     path,
+		Header:   h,
+		Encoding: strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))),
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		ct = "text/plain; charset=us-ascii" // bawaan RFC 2045
+	}
+	mt, params, err := mime.ParseMediaType(ct)
+	if err != nil && !errors.Is(err, mime.ErrInvalidMediaParameter) {
+		mt, params = "text/plain", map[string]string{}
+	}
+	p.ContentType, p.Params = mt, params
+	if d, dp, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		p.Disposition, p.Filename = d, dp["filename"]
+	}
+	if p.Filename == "" {
+		p.Filename = params["name"]
+	}
+	if v, err := wordDecoder.DecodeHeader(p.Filename); err == nil {
+		p.Filename = v
+	}
+
+	if strings.HasPrefix(mt, "multipart/") && params["boundary"] != "" && depth < maxMIMEDepth {
+		mr := multipart.NewReader(body, params["boundary"])
+		for i := 1; ; i++ {
+			// NextRawPart agar quoted-printable tidak didekode diam-diam dan
+			// header Content-Transfer-Encoding tetap terlihat
+			rp, err := mr.NextRawPart()
+			if err == io.EOF {
+				return p, nil
+			}
+			if err != nil {
+				return p, fmt.Errorf("part %s: %w", childPath(path, i), err)
+			}
+			child, err := parsePart(rp.Header, rp, childPath(path, i), depth+1)
+			p.Parts = append(p.Parts, child)
+			if err != nil {
+				return p, err
+			}
+		}
+	}
+
+	if p.Path == "" {
+		p.Path = "1" // root yang bukan multipart
+	}
+	p.Body, err = io.ReadAll(decodeTransfer(body, p.Encoding))
+	if err != nil {
+		return p, fmt.Errorf("part %s: %w", p.Path, err)
+	}
+	if p.Body == nil {
+		p.Body = []byte{}
+	}
+	// pesan terlampir diurai juga sebagai anak
+	if mt == "message/rfc822" && depth < maxMIMEDepth {
+		if inner, err := mail.ReadMessage(bytes.NewReader(p.Body)); err == nil {
+			child, err := parsePart(textproto.MIMEHeader(inner.Header), inner.Body, childPath(p.Path, 1), depth+1)
+			p.Parts = append(p.Parts, child)
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func childPath(parent string, i int) string {
+	if parent == "" {
+		return strconv.Itoa(i)
+	}
+	return parent + "." + strconv.Itoa(i)
+}
+
+func decodeTransfer(r io.Reader, encoding string) io.Reader {
+	switch encoding {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r // 7bit, 8bit, binary
+}
+
+// decodeCharset mengubah isi ke UTF-8 untuk charset yang bisa ditangani
+// tanpa dependensi luar.
+func decodeCharset(charset string, b []byte) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return string(b), true
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		// windows-1252 hanya berbeda di 0x80-0x9F; cukup mendekati untuk dibaca
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r), true
+	}
+	return string(b), false
+}
+
+// Text mengembalikan isi bagian sebagai teks UTF-8 sesuai charset-nya.
+func (p *Part) Text() string {
+	s, _ := decodeCharset(p.Params["charset"], p.Body)
+	return s
+}
+
+// Walk mengiterasi p dan semua turunannya (depth-first) beserta kedalamannya.
+func (p *Part) Walk() iter.Seq2[int, *Part] {
+	return func(yield func(int, *Part) bool) {
+		p.walk(0, yield)
+	}
+}
+
+func (p *Part) walk(depth int, yield func(int, *Part) bool) bool {
+	if !yield(depth, p) {
+		return false
+	}
+	for _, c := range p.Parts {
+		if !c.walk(depth+1, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find mencari bagian dengan nomor path, mis. "1.2".
+func (pm *ParsedMessage) Find(path string) *Part {
+	for _, p := range pm.Root.Walk() {
+		if p.Path == path {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package mailtm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseFixture(t *testing.T, name string) *ParsedMessage {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pm, err := ParseMIME(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm
+}
+
+func TestParseMIMEHeaders(t *testing.T) {
+	pm := parseFixture(t, "nested.eml")
+	want := []HeaderField{
+		{"From", "Layanan Café <noreply@example.test>"},
+		{"To", "bob@example.test"},
+		{"Subject", "Kode verifikasi – Café"},
+		{"Subject", "duplikat"},
+		{"X-Long", "baris pertama baris kedua"},
+	}
+	for i, w := range want {
+		if pm.Headers[i] != w {
+			t.Fatalf("header %d = %+v, want %+v", i, pm.Headers[i], w)
+		}
+	}
+}
+
+func TestParseMIMETree(t *testing.T) {
+	pm := parseFixture(t, "nested.eml")
+	var got []string
+	for depth, p := range pm.Root.Walk() {
+		got = append(got, strings.Repeat(" ", depth)+p.Path+" "+p.ContentType)
+	}
+	want := []string{
+		" multipart/mixed",
+		" 1 multipart/alternative",
+		"  1.1 text/plain",
+		"  1.2 text/html",
+		" 2 text/plain",
+		" 3 application/pdf",
+		" 4 text/plain",
+		" 5 message/rfc822",
+		"  5.1 multipart/alternative",
+		"   5.1.1 text/plain",
+		"   5.1.2 text/html",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("tree:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseMIMEBodies(t *testing.T) {
+	pm := parseFixture(t, "nested.eml")
+	tests := []struct {
+		path, encoding, text string
+	}{
+		{"1.1", "quoted-printable", "Kode Anda: 123456. Selamat datang di Café, baris ini panjang sekali dan disambung."},
+		{"1.2", "base64", "<p>Halo <b>dunia</b> ✓</p>"},
+		{"2", "quoted-printable", "café"},
+		{"3", "base64", "%PDF-1.4 palsu"},
+		{"5.1.1", "", "isi terlampir"},
+	}
+	for _, tt := range tests {
+		p := pm.Find(tt.path)
+		if p == nil {
+			t.Fatalf("part %s not found", tt.path)
+		}
+		if p.Encoding != tt.encoding || strings.TrimSpace(p.Text()) != tt.text {
+			t.Errorf("part %s: encoding %q, text %q; want %q, %q", tt.path, p.Encoding, p.Text(), tt.encoding, tt.text)
+		}
+	}
+	if p := pm.Find("5"); !strings.Contains(string(p.Body), "Subject: =?ISO-8859-1?Q?Halo_dar=E9?=") {
+		t.Errorf("rfc822 part body = %q", p.Body)
+	}
+	if p := pm.Find("5.1"); p == nil || p.Header.Get("Content-Type") == "" {
+		t.Error("embedded message headers not parsed")
+	}
+}
+
+func TestParseMIMEFilenames(t *testing.T) {
+	pm := parseFixture(t, "nested.eml")
+	for path, want := range map[string]string{
+		"3": "laporan ümlaut.pdf", // RFC 2047
+		"4": "catatan ü.txt",      // RFC 2231
+	} {
+		p := pm.Find(path)
+		if p.Disposition != "attachment" || p.Filename != want {
+			t.Errorf("part %s: disposition %q, filename %q; want %q", path, p.Disposition, p.Filename, want)
+		}
+	}
+}
+
+func TestParseMIMEPlain(t *testing.T) {
+	pm := parseFixture(t, "plain.eml")
+	if pm.Root.Path != "1" || pm.Root.ContentType != "text/plain" || pm.Root.Params["charset"] != "us-ascii" {
+		t.Fatalf("root = %+v", pm.Root)
+	}
+	if strings.TrimSpace(pm.Root.Text()) != "tanpa Content-Type" {
+		t.Fatalf("text = %q", pm.Root.Text())
+	}
+}
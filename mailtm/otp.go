@@ -0,0 +1,188 @@
+package mailtm
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ========================= Kode verifikasi (OTP) =========================
+
+// Code adalah kandidat kode sekali pakai; Score makin tinggi makin mungkin.
+type Code struct {
+	Value string
+	Score int
+}
+
+var (
+	// 4-8 digit, boleh dipisah satu spasi atau tanda hubung di tengah (123 456)
+	digitCodeRe = regexp.MustCompile(`\b\d{3,4}[ -]\d{3,4}\b|\b\d{4,8}\b`)
+	// token alfanumerik huruf besar/angka, mis. K7Q-2PZ atau A1B2C3
+	alnumCodeRe = regexp.MustCompile(`\b[A-Z0-9]{3,5}-[A-Z0-9]{3,5}\b|\b[A-Za-z0-9]{5,10}\b`)
+	codeWordRe  = regexp.MustCompile(`(?i)\b(code|kode|codigo|código|verification|verify|verifikasi|otp|pin|passcode|one[- ]time|security|keamanan|token|konfirmasi|confirmation|confirm)\b`)
+	yearRe      = regexp.MustCompile(`^(19|20)\d\d$`)
+	// angka ini lanjutan dari nomor lain (telepon, rekening) di depannya
+	numberTailRe = regexp.MustCompile(`(?i)([+\d][\d()-]*\s?|\b(call|tel|phone|telp|hp|wa|hubungi)\W*)$`)
+
+	tagRe       = regexp.MustCompile(`(?s)<(script|style|head)\b.*?</(script|style|head)>|<[^>]+>`)
+	blockTagRe  = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h[1-6])\b[^>]*>`)
+	blankLineRe = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// HTMLToText membuang tag dan entity HTML menjadi teks biasa.
+func HTMLToText(s string) string {
+	s = blockTagRe.ReplaceAllString(s, "\n")
+	s = tagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return strings.TrimSpace(blankLineRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// PlainText mengembalikan isi teks pesan, atau HTML yang dijadikan teks bila
+// pesan tidak punya bagian text.
+func (m *Message) PlainText() string {
+	if strings.TrimSpace(m.Text) != "" {
+		return m.Text
+	}
+	return HTMLToText(m.HTML.String())
+}
+
+// ExtractCodes mencari kandidat kode verifikasi di subjek dan isi pesan,
+// diurutkan dari yang paling mungkin.
+func ExtractCodes(m *Message) []Code {
+	codes := FindCodes(m.Subject)
+	for i := range codes {
+		codes[i].Score += 5 // kode di subjek biasanya memang kodenya
+	}
+	return mergeCodes(codes, FindCodes(m.PlainText()))
+}
+
+// FindCodes mencari kandidat kode verifikasi di teks.
+func FindCodes(text string) []Code {
+	var out []Code
+	add := func(loc []int, alnum bool) {
+		raw := text[loc[0]:loc[1]]
+		value := strings.NewReplacer(" ", "", "-", "").Replace(raw)
+		score := scoreCode(text, loc, value, alnum)
+		if score > 0 {
+			out = mergeCodes(out, []Code{{Value: value, Score: score}})
+		}
+	}
+	for _, loc := range digitCodeRe.FindAllStringIndex(text, -1) {
+		add(loc, false)
+	}
+	for _, loc := range alnumCodeRe.FindAllStringIndex(text, -1) {
+		add(loc, true)
+	}
+	return out
+}
+
+func scoreCode(text string, loc []int, value string, alnum bool) int {
+	if inURL(text, loc[0]) {
+		return 0 // kata kunci di path URL (mis. /verify?id=) tidak dihitung
+	}
+	score := 0
+	if alnum {
+		hasDigit := strings.ContainsAny(value, "0123456789")
+		hasLetter := strings.ToLower(value) != strings.ToUpper(value)
+		// token alfanumerik hanya dihitung bila campuran huruf+angka dan
+		// huruf besar semua, atau ada kata kunci di dekatnya
+		switch {
+		case !hasDigit || !hasLetter:
+			return 0
+		case value == strings.ToUpper(value):
+			score = 4
+		default:
+			score = 1
+		}
+	} else {
+		score = 5
+		if len(value) == 6 {
+			score += 5
+		}
+		if yearRe.MatchString(value) {
+			score -= 12
+		}
+	}
+
+	before := text[max(0, loc[0]-48):loc[0]]
+	after := text[loc[1]:min(len(text), loc[1]+24)]
+	if codeWordRe.MatchString(before) {
+		score += 20
+	} else if codeWordRe.MatchString(after) {
+		score += 10
+	} else if alnum {
+		return 0
+	}
+
+	// bagian dari harga, nomor pesanan, tanggal, jam, URL atau nomor telepon
+	prev, next := lastByte(before), firstByte(after)
+	trimmed := strings.TrimRight(before, " ")
+	switch {
+	case hasAnySuffix(trimmed, "$", "€", "£", "¥", "Rp", "IDR", "USD", "EUR", "#", "No.", "no."):
+		score -= 15
+	case prev == '/' || prev == '.' || prev == ':' || prev == '=' || prev == '?' || prev == '&':
+		score -= 15
+	case next == '/' || next == '%' || next == ':' || (next == '.' && len(after) > 1 && isDigit(after[1])):
+		score -= 15
+	case numberTailRe.MatchString(before):
+		score -= 15
+	}
+	return score
+}
+
+// MinCodeScore: kandidat dengan skor di bawah ini dianggap bukan kode.
+const MinCodeScore = 10
+
+// BestCode mengembalikan kandidat kode terbaik bila cukup meyakinkan.
+func BestCode(m *Message) (Code, bool) {
+	codes := ExtractCodes(m)
+	if len(codes) == 0 || codes[0].Score < MinCodeScore {
+		return Code{}, false
+	}
+	return codes[0], true
+}
+
+func mergeCodes(a, b []Code) []Code {
+	for _, c := range b {
+		i := slices.IndexFunc(a, func(x Code) bool { return x.Value == c.Value })
+		if i < 0 {
+			a = append(a, c)
+		} else if c.Score > a[i].Score {
+			a[i].Score = c.Score
+		}
+	}
+	slices.SortStableFunc(a, func(x, y Code) int { return y.Score - x.Score })
+	return a
+}
+
+// inURL melaporkan apakah posisi i berada di dalam URL, yaitu kata yang
+// memuat i diawali skema atau www.
+func inURL(text string, i int) bool {
+	word := text[strings.LastIndexAny(text[:i], " \t\r\n<>\"'")+1 : i]
+	return strings.Contains(word, "://") || strings.HasPrefix(strings.ToLower(word), "www.")
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	return slices.ContainsFunc(suffixes, func(x string) bool { return strings.HasSuffix(s, x) })
+}
+
+func lastByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[len(s)-1]
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
@@ -0,0 +1,53 @@
+package mailtm
+
+import "testing"
+
+func TestBestCode(t *testing.T) {
+	tests := []struct {
+		name, subject, text string
+		want                string // "" berarti tidak boleh ada kode
+	}{
+		{"kode dengan kata kunci", "", "Your verification code is 482913.", "482913"},
+		{"kata kunci sesudah kode", "", "482913 is your code", "482913"},
+		{"kode dipisah spasi", "", "Kode verifikasi: 123 456", "123456"},
+		{"kode dipisah tanda hubung", "", "Your code: 123-456", "123456"},
+		{"kode di subjek", "Kode Anda 5521", "Terima kasih sudah mendaftar. Nomor pelanggan 88123401.", "5521"},
+		{"subjek mengalahkan isi", "Your code: 918273", "Use code 111222 for a discount.", "918273"},
+		{"harga dolar", "", "Total: $1299 for your order", ""},
+		{"harga rupiah", "", "Tagihan Rp 150000 sudah dibayar", ""},
+		{"harga dengan kode", "", "Paid $4999. Your code is 731904.", "731904"},
+		{"tahun", "", "© 2024 Example Inc. All rights reserved.", ""},
+		{"tahun di samping kode", "", "Copyright 2024. Your code: 7391", "7391"},
+		{"nomor telepon", "", "Questions? Call +1 800 5551234 anytime.", ""},
+		{"telepon dengan kata", "", "Hubungi 08123456 untuk bantuan", ""},
+		{"telepon dan kode", "", "Call 555-1234 for help. Your OTP is 246810.", "246810"},
+		{"angka di URL", "", "Open https://example.com/u/483920/confirm to continue", ""},
+		{"query URL", "", "Visit https://example.com/verify?id=112233 now", ""},
+		{"nomor pesanan", "", "Order #482913 has shipped", ""},
+		{"jam", "", "Meeting at 1230:45 tomorrow", ""},
+		{"alfanumerik dengan kata kunci", "", "Your verification code: K7Q2PZ", "K7Q2PZ"},
+		{"alfanumerik dengan tanda hubung", "", "Security code K7Q-2PZ", "K7Q2PZ"},
+		{"alfanumerik tanpa kata kunci", "", "Reference AB12CD attached to this email", ""},
+		{"kata biasa dengan kata kunci", "", "Your code is below: hello", ""},
+		{"huruf kecil campuran tanpa kata kunci", "", "see file abc123x for details", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Message{Subject: tt.subject, Text: tt.text}
+			c, ok := BestCode(m)
+			switch {
+			case tt.want == "" && ok:
+				t.Fatalf("got %q (score %d), want none; all: %v", c.Value, c.Score, ExtractCodes(m))
+			case tt.want != "" && c.Value != tt.want:
+				t.Fatalf("got %q, %v; want %q; all: %v", c.Value, ok, tt.want, ExtractCodes(m))
+			}
+		})
+	}
+}
+
+func TestFindCodesDedup(t *testing.T) {
+	codes := FindCodes("Code 123 456. Again, your code is 123456.")
+	if len(codes) != 1 || codes[0].Value != "123456" {
+		t.Fatalf("got %v", codes)
+	}
+}
@@ -0,0 +1,239 @@
+package mailtm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ========================= Mercure (SSE) =========================
+
+const DefaultMercureURL = "https://mercure.mail.tm/.well-known/mercure"
+
+var (
+	errSSEUnavailable = errors.New("mercure hub unavailable")
+	// ErrStopWatch bisa dikembalikan fn WatchMessages untuk berhenti tanpa error.
+	ErrStopWatch = errors.New("stop watching")
+)
+
+type sseEvent struct {
+	ID    string // Last-Event-ID terakhir yang diketahui
+	Event string
+	Data  string
+}
+
+// readSSE mem-parse stream text/event-stream dan memanggil fn untuk setiap event.
+func readSSE(r io.Reader, fn func(sseEvent)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var ev sseEvent
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if len(data) > 0 {
+				ev.Data = strings.Join(data, "\n")
+				fn(ev)
+			}
+			ev.Event, ev.Data, data = "", "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // komentar / heartbeat
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			ev.Event = value
+		case "id":
+			ev.ID = value
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// streamEvents membuka satu koneksi ke hub dan membaca sampai putus. opened
+// dipanggil sekali setelah hub menerima langganan, sebelum event pertama
+// dibaca. connected bernilai true jika hub sempat menerima langganan.
+func (c *Client) streamEvents(ctx context.Context, lastID string, opened func() error, fn func(sseEvent)) (string, bool, error) {
+	u := c.MercureURL + "?topic=" + url.QueryEscape("/accounts/"+c.AccountID)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return lastID, false, err
+	}
+	if err := c.ensureToken(ctx); err != nil {
+		return lastID, false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	// jangan lewat c.do: timeout-nya akan memutus stream yang panjang
+	res, err := c.http.Do(req)
+	if err != nil {
+		return lastID, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		return lastID, false, fmt.Errorf("%w: status %d", errSSEUnavailable, res.StatusCode)
+	}
+	if err := opened(); err != nil {
+		return lastID, true, err
+	}
+	err = readSSE(res.Body, func(ev sseEvent) {
+		lastID = ev.ID
+		fn(ev)
+	})
+	return lastID, true, err
+}
+
+type mercureUpdate struct {
+	Type string `json:"@type"`
+	Message
+}
+
+// watchSSE mengirim pesan baru dari hub Mercure ke fn, menyambung ulang dengan
+// Last-Event-ID bila koneksi putus. Mengembalikan errSSEUnavailable jika hub
+// tidak bisa dipakai sehingga pemanggil bisa beralih ke polling.
+func (c *Client) watchSSE(ctx context.Context, seen map[string]bool, check func() error, fn func(Message) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lastID string
+	var fnErr error
+	everConnected := false
+	failures := 0
+	for {
+		var connected bool
+		var err error
+		// pesan yang tiba sebelum langganan aktif (di antara daftar awal dan
+		// koneksi pertama, atau saat koneksi putus) tidak dikirim hub
+		opened := func() error {
+			fnErr = check()
+			return fnErr
+		}
+		lastID, connected, err = c.streamEvents(ctx, lastID, opened, func(ev sseEvent) {
+			if fnErr != nil {
+				return
+			}
+			var up mercureUpdate
+			if json.Unmarshal([]byte(ev.Data), &up) == nil && up.Type == "Message" && up.ID != "" {
+				if !seen[up.ID] {
+					seen[up.ID] = true
+					fnErr = fn(up.Message)
+				}
+			} else {
+				// update akun (mis. kuota terpakai): cek ulang daftar pesan
+				fnErr = check()
+			}
+			if fnErr != nil {
+				cancel()
+			}
+		})
+		if fnErr != nil {
+			return fnErr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			everConnected = true
+			failures = 0
+		} else {
+			failures++
+		}
+		if !everConnected || failures >= 5 {
+			return fmt.Errorf("%w: %v", errSSEUnavailable, err)
+		}
+		backoff := time.Duration(1<<min(failures, 5)) * time.Second
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// WatchMessages memanggil fn untuk setiap pesan baru sejak fungsi ini dipanggil,
+// sampai ctx selesai atau fn mengembalikan error. Memakai hub Mercure bila
+// tersedia, dan kembali ke polling setiap interval jika tidak.
+func (c *Client) WatchMessages(ctx context.Context, interval time.Duration, fn func(Message) error) error {
+	return c.watchMessages(ctx, interval, time.Time{}, fn)
+}
+
+// watchMessages seperti WatchMessages, tetapi pesan yang sudah ada dan
+// diterima setelah since (bila diisi) ikut dikirim ke fn lebih dulu.
+func (c *Client) watchMessages(ctx context.Context, interval time.Duration, since time.Time, fn func(Message) error) error {
+	msgs, err := c.GetMessages(ctx)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(msgs))
+	pending := false
+	for _, m := range msgs {
+		if !since.IsZero() && receivedAfter(&m, since) {
+			pending = true
+			continue
+		}
+		seen[m.ID] = true
+	}
+	check := func() error {
+		cur, err := c.GetMessages(ctx)
+		if err != nil {
+			return err
+		}
+		// terbaru dulu; kirim yang paling lama lebih dulu
+		for i := len(cur) - 1; i >= 0; i-- {
+			if seen[cur[i].ID] {
+				continue
+			}
+			seen[cur[i].ID] = true
+			if err := fn(cur[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if pending {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+
+	if c.MercureURL != "" && c.AccountID != "" {
+		err := c.watchSSE(ctx, seen, check, fn)
+		if !errors.Is(err, errSSEUnavailable) {
+			return err
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if err := check(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package mailtm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadSSE(t *testing.T) {
+	stream := ": heartbeat\n\n" +
+		"id: e1\nevent: update\ndata: {\"a\":1}\n\n" +
+		"data: baris 1\ndata:baris 2\n\n" +
+		"id: e3\n\n" + // tanpa data: tidak dikirim, tapi id tetap berlaku
+		"data: x\n\n" +
+		"data: belum selesai\n"
+	var got []sseEvent
+	err := readSSE(strings.NewReader(stream), func(ev sseEvent) { got = append(got, ev) })
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+	want := []sseEvent{
+		{ID: "e1", Event: "update", Data: `{"a":1}`},
+		{ID: "e1", Data: "baris 1\nbaris 2"},
+		{ID: "e3", Data: "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %+v\nwant %+v", got, want)
+	}
+}
+
+// sseStub meniru GET /messages dan hub Mercure untuk satu akun.
+type sseStub struct {
+	mu       sync.Mutex
+	messages []Message // terbaru dulu
+	lastIDs  []string  // Last-Event-ID tiap koneksi hub
+	hub      func(w http.ResponseWriter, r *http.Request, conn int)
+}
+
+func (s *sseStub) add(m Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append([]Message{m}, s.messages...)
+}
+
+func (s *sseStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/messages":
+		s.mu.Lock()
+		body, _ := json.Marshal(hydraMessages{Members: s.messages, TotalItems: len(s.messages)})
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/ld+json")
+		w.Write(body)
+	case "/hub":
+		s.mu.Lock()
+		s.lastIDs = append(s.lastIDs, r.Header.Get("Last-Event-ID"))
+		conn := len(s.lastIDs)
+		s.mu.Unlock()
+		s.hub(w, r, conn)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newSSEClient(t *testing.T, stub *sseStub) *Client {
+	t.Helper()
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	c := New(WithBaseURL(srv.URL), WithMercureURL(srv.URL+"/hub"), WithHTTPClient(srv.Client()))
+	c.Token, c.AccountID = "token", "acc1"
+	return c
+}
+
+func sseFrame(id string, m Message) string {
+	b, _ := json.Marshal(mercureUpdate{Type: "Message", Message: m})
+	return fmt.Sprintf("id: %s\ndata: %s\n\n", id, b)
+}
+
+// collect menjalankan WatchMessages sampai n pesan diterima.
+func collect(t *testing.T, c *Client, n int) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var ids []string
+	err := c.WatchMessages(ctx, 50*time.Millisecond, func(m Message) error {
+		ids = append(ids, m.ID)
+		if len(ids) == n {
+			return ErrStopWatch
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrStopWatch) {
+		t.Fatalf("WatchMessages = %v after %v", err, ids)
+	}
+	return ids
+}
+
+func TestWatchSSEReconnectsWithLastEventID(t *testing.T) {
+	stub := &sseStub{}
+	stub.hub = func(w http.ResponseWriter, r *http.Request, conn int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch conn {
+		case 1:
+			// koneksi pertama putus setelah satu event
+			fmt.Fprint(w, sseFrame("ev-1", Message{ID: "m1"}))
+		case 2:
+			fmt.Fprint(w, sseFrame("ev-2", Message{ID: "m2"}))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}
+	c := newSSEClient(t, stub)
+	if ids := collect(t, c, 2); !reflect.DeepEqual(ids, []string{"m1", "m2"}) {
+		t.Fatalf("messages = %v", ids)
+	}
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if !reflect.DeepEqual(stub.lastIDs, []string{"", "ev-1"}) {
+		t.Fatalf("Last-Event-ID per connection = %q", stub.lastIDs)
+	}
+}
+
+func TestWatchFallsBackToPolling(t *testing.T) {
+	hubs := map[string]func(w http.ResponseWriter, r *http.Request, conn int){
+		"error": func(w http.ResponseWriter, r *http.Request, conn int) {
+			http.Error(w, "hub down", http.StatusBadGateway)
+		},
+		"not sse": func(w http.ResponseWriter, r *http.Request, conn int) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, "{}")
+		},
+	}
+	for name, hub := range hubs {
+		t.Run(name, func(t *testing.T) {
+			stub := &sseStub{hub: hub}
+			stub.add(Message{ID: "lama"})
+			c := newSSEClient(t, stub)
+			go func() {
+				time.Sleep(200 * time.Millisecond)
+				stub.add(Message{ID: "baru"})
+			}()
+			if ids := collect(t, c, 1); !reflect.DeepEqual(ids, []string{"baru"}) {
+				t.Fatalf("messages = %v", ids)
+			}
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if len(stub.lastIDs) != 1 {
+				t.Fatalf("hub contacted %d times, want 1", len(stub.lastIDs))
+			}
+		})
+	}
+}
+
+// Pesan yang tiba setelah daftar awal diambil tetapi sebelum langganan
+// aktif tidak dikirim hub, jadi harus ditemukan lewat daftar pesan.
+func TestWatchSSECatchesMessageBeforeSubscribe(t *testing.T) {
+	stub := &sseStub{}
+	stub.hub = func(w http.ResponseWriter, r *http.Request, conn int) {
+		if conn == 1 {
+			stub.add(Message{ID: "celah"})
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ":\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}
+	c := newSSEClient(t, stub)
+	if ids := collect(t, c, 1); !reflect.DeepEqual(ids, []string{"celah"}) {
+		t.Fatalf("messages = %v", ids)
+	}
+}
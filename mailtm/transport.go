@@ -0,0 +1,197 @@
+package mailtm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ========================= Transport HTTP =========================
+
+// Mail.tm membatasi jumlah request per IP (sekitar 8/detik). Transport
+// menahan request dengan token bucket dan mencoba ulang 429/5xx dengan
+// backoff eksponensial + jitter yang menghormati Retry-After.
+
+const (
+	DefaultRateLimit = 8 // request per detik
+	DefaultRetries   = 4
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// rateLimiter adalah token bucket sederhana; rate <= 0 berarti tanpa batas.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // token per detik
+	burst  float64
+	tokens float64
+	last   time.Time
+	until  time.Time // semua request ditahan sampai waktu ini (Retry-After)
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	b := float64(max(burst, 1))
+	return &rateLimiter{rate: rate, burst: b, tokens: b}
+}
+
+// wait menunggu sampai satu token tersedia atau ctx selesai.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		d := l.reserve()
+		if d <= 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reserve mengambil token jika ada, atau mengembalikan lama menunggu.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.until) {
+		return l.until.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// pause menahan semua request sampai d berlalu, dipakai setelah 429.
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t := time.Now().Add(d); t.After(l.until) {
+		l.until = t
+	}
+}
+
+type retryTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+	retries int // jumlah percobaan ulang per request
+}
+
+// NewTransport membungkus base (nil = http.DefaultTransport) dengan limiter
+// rate request/detik (0 = tanpa batas) dan hingga retries percobaan ulang.
+// Pakai satu transport untuk semua Client yang berbagi IP yang sama.
+func NewTransport(base http.RoundTripper, rate float64, retries int) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, limiter: newRateLimiter(rate, max(int(rate), 1)), retries: retries}
+}
+
+// defaultHTTP dipakai Client tanpa WithHTTPClient, dibagi dalam satu proses.
+var defaultHTTP = sync.OnceValue(func() HTTPDoer {
+	return &http.Client{Transport: NewTransport(nil, DefaultRateLimit, DefaultRetries)}
+})
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, errRetryBody
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		res, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !retryable(req, res, err) {
+			return res, err
+		}
+
+		delay := backoff(attempt)
+		if res != nil {
+			if ra, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+				delay = min(ra, retryMaxDelay)
+			}
+			if res.StatusCode == http.StatusTooManyRequests {
+				t.limiter.pause(delay)
+			}
+			// kosongkan body agar koneksi bisa dipakai ulang
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+var errRetryBody = errors.New("request body cannot be replayed for retry")
+
+// retryable: 429/502/503/504 aman diulang untuk semua method karena server
+// belum memproses request; 500 dan error jaringan hanya untuk method
+// idempoten.
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead ||
+		req.Method == http.MethodDelete || req.Method == http.MethodOptions
+	if err != nil {
+		return idempotent
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusInternalServerError:
+		return idempotent
+	}
+	return false
+}
+
+// backoff: eksponensial dengan jitter penuh di paruh atas interval.
+func backoff(attempt int) time.Duration {
+	d := min(retryBaseDelay<<attempt, retryMaxDelay)
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter mengurai Retry-After berupa detik atau tanggal HTTP.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
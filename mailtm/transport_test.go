@@ -0,0 +1,210 @@
+package mailtm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// retryServer membalas dengan status berikutnya dari statuses (yang terakhir
+// diulang) dan mencatat body setiap request.
+type retryServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newRetryServer(t *testing.T, retryAfter string, statuses ...int) *retryServer {
+	s := &retryServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(b))
+		status := statuses[min(len(s.bodies), len(statuses))-1]
+		s.mu.Unlock()
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *retryServer) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func roundTrip(t *testing.T, rt http.RoundTripper, req *http.Request) (int, error) {
+	t.Helper()
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.StatusCode, nil
+}
+
+func TestRetryBudget(t *testing.T) {
+	srv := newRetryServer(t, "0", http.StatusServiceUnavailable)
+	rt := NewTransport(nil, 0, 2)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	status, err := roundTrip(t, rt, req)
+	if err != nil || status != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, err %v", status, err)
+	}
+	if n := srv.calls(); n != 3 {
+		t.Fatalf("got %d calls, want 1 + 2 retries", n)
+	}
+}
+
+func TestRetryRecovers(t *testing.T) {
+	srv := newRetryServer(t, "0", http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusOK)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if status, err := roundTrip(t, NewTransport(nil, 0, 4), req); err != nil || status != http.StatusOK {
+		t.Fatalf("status %d, err %v", status, err)
+	}
+	if n := srv.calls(); n != 3 {
+		t.Fatalf("got %d calls", n)
+	}
+}
+
+func TestNoRetryPost500(t *testing.T) {
+	srv := newRetryServer(t, "0", http.StatusInternalServerError, http.StatusOK)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"a":1}`))
+	status, err := roundTrip(t, NewTransport(nil, 0, 4), req)
+	if err != nil || status != http.StatusInternalServerError {
+		t.Fatalf("status %d, err %v", status, err)
+	}
+	if n := srv.calls(); n != 1 {
+		t.Fatalf("POST retried after 500: %d calls", n)
+	}
+
+	// GET boleh diulang setelah 500
+	srv = newRetryServer(t, "0", http.StatusInternalServerError, http.StatusOK)
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	if status, _ := roundTrip(t, NewTransport(nil, 0, 4), req); status != http.StatusOK || srv.calls() != 2 {
+		t.Fatalf("GET: status %d after %d calls", status, srv.calls())
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	srv := newRetryServer(t, "0", http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusCreated)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"address":"a@example.test"}`))
+	status, err := roundTrip(t, NewTransport(nil, 0, 4), req)
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("status %d, err %v", status, err)
+	}
+	for i, b := range srv.bodies {
+		if b != `{"address":"a@example.test"}` {
+			t.Fatalf("attempt %d sent body %q", i, b)
+		}
+	}
+
+	// body tanpa GetBody tidak bisa diulang
+	srv = newRetryServer(t, "0", http.StatusServiceUnavailable)
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, io.NopCloser(strings.NewReader("x")))
+	if _, err := roundTrip(t, NewTransport(nil, 0, 4), req); !errors.Is(err, errRetryBody) {
+		t.Fatalf("err = %v, want errRetryBody", err)
+	}
+	if n := srv.calls(); n != 1 {
+		t.Fatalf("got %d calls", n)
+	}
+}
+
+func TestRetryCancelDuringBackoff(t *testing.T) {
+	// tanpa Retry-After backoff pertama minimal retryBaseDelay/2
+	srv := newRetryServer(t, "", http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	_, err := roundTrip(t, NewTransport(nil, 0, 4), req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if d := time.Since(start); d >= retryBaseDelay/2 {
+		t.Fatalf("returned after %v, backoff was not interrupted", d)
+	}
+	if n := srv.calls(); n != 1 {
+		t.Fatalf("got %d calls", n)
+	}
+}
+
+func TestRetryAfter429PausesLimiter(t *testing.T) {
+	srv := newRetryServer(t, "1", http.StatusTooManyRequests, http.StatusOK)
+	rt := NewTransport(nil, 0, 1).(*retryTransport)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	if status, err := roundTrip(t, rt, req); err != nil || status != http.StatusOK {
+		t.Fatalf("status %d, err %v", status, err)
+	}
+	if d := time.Since(start); d < 900*time.Millisecond {
+		t.Fatalf("retried after %v, want Retry-After 1s", d)
+	}
+	if rt.limiter.until.IsZero() {
+		t.Fatal("429 did not pause the shared limiter")
+	}
+}
+
+func TestRetryAfterParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   time.Duration
+		ok     bool
+		approx bool
+	}{
+		{"", 0, false, false},
+		{"0", 0, true, false},
+		{"120", 120 * time.Second, true, false},
+		{"-1", 0, false, false},
+		{"soon", 0, false, false},
+		{time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 10 * time.Second, true, true},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", 0, true, false}, // sudah lewat
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.in)
+		if ok != tt.ok {
+			t.Errorf("retryAfter(%q) ok = %v", tt.in, ok)
+			continue
+		}
+		if tt.approx {
+			if got <= tt.want-2*time.Second || got > tt.want {
+				t.Errorf("retryAfter(%q) = %v, want about %v", tt.in, got, tt.want)
+			}
+		} else if got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := newRateLimiter(10, 2)
+	if d := l.reserve(); d != 0 {
+		t.Fatalf("first token: wait %v", d)
+	}
+	if d := l.reserve(); d != 0 {
+		t.Fatalf("burst token: wait %v", d)
+	}
+	if d := l.reserve(); d <= 0 || d > 100*time.Millisecond {
+		t.Fatalf("empty bucket: wait %v, want up to 100ms", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.pause(time.Hour)
+	if err := l.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait = %v", err)
+	}
+	if err := newRateLimiter(0, 0).wait(context.Background()); err != nil {
+		t.Fatalf("unlimited wait = %v", err)
+	}
+}
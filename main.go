@@ -2,19 +2,25 @@ package main
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
 )
 
 /*
@@ -27,105 +33,446 @@ Porting dari script Python:
 // ========================= Storage =========================
 
 type Account struct {
-	Address   string `json:"address"`
-	Password  string `json:"password"`
-	AccountID string `json:"account_id"`
-	Nickname  string `json:"nickname"`
+	Address   string   `json:"address"`
+	Password  string   `json:"password"`
+	AccountID string   `json:"account_id"`
+	Nickname  string   `json:"nickname"`
+	Token     string   `json:"token,omitempty"`
+	TokenExp  int64    `json:"token_exp,omitempty"` // klaim exp JWT (unix detik)
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// HasTag melaporkan apakah akun punya salah satu dari tags.
+func (a Account) HasTag(tags ...string) bool {
+	for _, t := range tags {
+		if slices.Contains(a.Tags, normalizeTag(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeTag(t string) string {
+	return strings.ToLower(strings.TrimSpace(t))
 }
 
 type Storage struct {
 	File     string
 	Accounts map[string]Account
+	sealer   *sealer // nil = file disimpan sebagai JSON biasa
+	loadErr  error   // file ada tapi gagal dibaca; jangan ditimpa
+	// mu melindungi Accounts saat satu Storage dipakai banyak goroutine
+	// (mis. token dari watch semua akun); antar-proses dijaga file lock.
+	mu sync.Mutex
 }
 
+// storeBackups: jumlah salinan .bak.N yang disimpan sebelum setiap Save.
+const storeBackups = 5
+
+// NewStorage tidak pernah gagal; cek Err() untuk mengetahui apakah file
+// berhasil dibaca. Selama Err() != nil, Save menolak menimpa file.
 func NewStorage(file string) *Storage {
 	s := &Storage{File: file, Accounts: map[string]Account{}}
 	_ = s.Load()
 	return s
 }
 
+func (s *Storage) Err() error {
+	return s.loadErr
+}
+
 func (s *Storage) Load() error {
-	f, err := os.Open(s.File)
+	s.Accounts = map[string]Account{}
+	s.sealer = nil
+	s.loadErr = nil
+	accts, sl, err := readStoreFile(s.File, nil)
 	if err != nil {
-		// tidak ada file = kosong
-		s.Accounts = map[string]Account{}
-		return nil
+		if errors.Is(err, os.ErrNotExist) {
+			// tidak ada file = kosong
+			return nil
+		}
+		s.loadErr = fmt.Errorf("load %s: %w", s.File, err)
+		return s.loadErr
+	}
+	s.Accounts = accts
+	s.sealer = sl
+	return nil
+}
+
+// readStoreFile membaca file akun, baik JSON biasa maupun terenkripsi.
+// known (boleh nil) dipakai ulang jika parameter KDF file tidak berubah.
+func readStoreFile(file string, known *sealer) (map[string]Account, *sealer, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sl *sealer
+	// format terenkripsi dikenali otomatis
+	if ef := parseEncrypted(data); ef != nil {
+		pass, err := storePassphrase()
+		if err != nil {
+			return nil, nil, err
+		}
+		plain, s, err := openSealed(ef, pass, known)
+		if err != nil {
+			return nil, nil, err
+		}
+		cachedPassphrase = pass
+		sl = s
+		data = plain
+	}
+	accts := map[string]Account{}
+	if err := json.Unmarshal(data, &accts); err != nil {
+		return nil, nil, err
 	}
-	defer f.Close()
-	dec := json.NewDecoder(f)
-	if err := dec.Decode(&s.Accounts); err != nil {
-		s.Accounts = map[string]Account{}
+	return accts, sl, nil
+}
+
+func backupName(file string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", file, n)
+}
+
+// rotateBackups menggeser .bak.N lalu menyalin file saat ini ke .bak.1.
+func (s *Storage) rotateBackups() error {
+	cur, err := os.ReadFile(s.File)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
 		return err
 	}
-	return nil
+	if len(cur) == 0 {
+		return nil
+	}
+	_ = os.Remove(backupName(s.File, storeBackups))
+	for i := storeBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(s.File, i), backupName(s.File, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.WriteFile(backupName(s.File, 1), cur, 0600)
 }
 
+// Save menimpa file dengan isi s.Accounts apa adanya. Untuk perubahan satu
+// akun pakai Add/Remove/SetToken/SetNickname, yang menggabungkan perubahan
+// dari proses lain.
 func (s *Storage) Save() error {
-	tmp := s.File + ".tmp"
-	f, err := os.Create(tmp)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loadErr != nil {
+		return fmt.Errorf("refusing to overwrite unreadable store (run 'mailtm recover'): %w", s.loadErr)
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.write(true)
+}
+
+// lock juga membuat direktori penyimpanan jika belum ada.
+func (s *Storage) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.File), 0700); err != nil {
+		return nil, err
+	}
+	return lockFile(s.File + ".lock")
+}
+
+// update membaca ulang file di bawah lock antar-proses, menerapkan fn pada
+// salinan terbaru itu, lalu menyimpannya, sehingga perubahan dari proses lain
+// tidak hilang.
+func (s *Storage) update(fn func(accts map[string]Account) error) error {
+	return s.updateWith(true, fn)
+}
+
+// updateWith seperti update; backup false melewati rotasi .bak.N untuk
+// perubahan yang tidak perlu dipulihkan, seperti token.
+func (s *Storage) updateWith(backup bool, fn func(accts map[string]Account) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loadErr != nil {
+		return fmt.Errorf("refusing to overwrite unreadable store (run 'mailtm recover'): %w", s.loadErr)
+	}
+	unlock, err := s.lock()
 	if err != nil {
 		return err
 	}
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(s.Accounts); err != nil {
+	defer unlock()
+	accts, sl, err := readStoreFile(s.File, s.sealer)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		accts, sl = map[string]Account{}, s.sealer
+	case err != nil:
+		return fmt.Errorf("reload %s: %w", s.File, err)
+	}
+	if err := fn(accts); err != nil {
+		return err
+	}
+	s.Accounts, s.sealer = accts, sl
+	return s.write(backup)
+}
+
+// write harus dipanggil sambil memegang lock. backup menggeser .bak.N lebih
+// dulu.
+func (s *Storage) write(backup bool) error {
+	if backup {
+		if err := s.rotateBackups(); err != nil {
+			return fmt.Errorf("backup %s: %w", s.File, err)
+		}
+	}
+	data, err := json.MarshalIndent(s.Accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.sealer != nil {
+		if data, err = s.sealer.seal(data); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(s.File, append(data, '\n'))
+}
+
+// writeFileAtomic menulis ke file tmp lalu rename, sehingga pembaca tidak
+// pernah melihat file setengah jadi.
+func writeFileAtomic(file string, data []byte) error {
+	// nama tmp unik per proses; CreateTemp membuat file 0600 sehingga hanya
+	// pemilik yang bisa membaca password
+	dir, base := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
 		f.Close()
 		_ = os.Remove(tmp)
 		return err
 	}
-	f.Close()
-	return os.Rename(tmp, s.File)
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	// fsync direktori agar rename tahan crash (diabaikan di Windows)
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
 }
 
-func (s *Storage) Add(address, password, accountID, nickname string) (string, error) {
-	key := nickname
-	if strings.TrimSpace(key) == "" {
-		// fallback: pakai address sebagai key unik
-		key = address
-	}
-	// jika key sudah ada & email beda, beri suffix
-	if _, ok := s.Accounts[key]; ok && s.Accounts[key].Address != address {
-		for i := 1; ; i++ {
-			k2 := fmt.Sprintf("%s_%d", key, i)
-			if _, clash := s.Accounts[k2]; !clash {
-				key = k2
-				break
+func (s *Storage) Encrypted() bool {
+	return s.sealer != nil
+}
+
+// Encrypt menyimpan ulang file dalam format terenkripsi dengan passphrase
+// baru. Juga dipakai untuk mengganti passphrase (rekey).
+func (s *Storage) Encrypt(passphrase string) error {
+	sl, err := newSealer(passphrase)
+	if err != nil {
+		return err
+	}
+	if err := s.reseal(sl); err != nil {
+		return err
+	}
+	cachedPassphrase = passphrase
+	return nil
+}
+
+// Decrypt menyimpan ulang file sebagai JSON biasa.
+func (s *Storage) Decrypt() error {
+	return s.reseal(nil)
+}
+
+// reseal menyimpan file dengan sealer baru (nil = JSON biasa), lalu
+// menyamakan format semua .bak.N. Tanpa itu backup masih menyimpan password
+// tanpa enkripsi setelah encrypt, atau masih bisa dibuka passphrase lama
+// setelah rekey.
+func (s *Storage) reseal(sl *sealer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loadErr != nil {
+		return fmt.Errorf("refusing to overwrite unreadable store (run 'mailtm recover'): %w", s.loadErr)
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	prev := s.sealer
+	s.sealer = sl
+	if err := s.write(true); err != nil {
+		s.sealer = prev
+		return err
+	}
+	return s.resealBackups(prev, cachedPassphrase)
+}
+
+// resealBackups menulis ulang setiap .bak.N dengan s.sealer. Backup
+// terenkripsi yang tidak bisa dibuka dengan passphrase lama (mis. dari
+// passphrase sebelumnya lagi) dihapus.
+func (s *Storage) resealBackups(prev *sealer, oldPass string) error {
+	for i := 1; i <= storeBackups; i++ {
+		name := backupName(s.File, i)
+		data, err := os.ReadFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("backup %s: %w", name, err)
+		}
+		if ef := parseEncrypted(data); ef != nil {
+			plain, _, err := openSealed(ef, oldPass, prev)
+			if err != nil {
+				if err := os.Remove(name); err != nil {
+					return fmt.Errorf("backup %s: %w", name, err)
+				}
+				continue
 			}
+			data = append(plain, '\n')
+		}
+		if s.sealer != nil {
+			if data, err = s.sealer.seal(data); err != nil {
+				return err
+			}
+			data = append(data, '\n')
+		}
+		if err := writeFileAtomic(name, data); err != nil {
+			return fmt.Errorf("backup %s: %w", name, err)
 		}
 	}
-	s.Accounts[key] = Account{
-		Address:   address,
-		Password:  password,
-		AccountID: accountID,
-		Nickname:  nickname,
-	}
-	if err := s.Save(); err != nil {
+	return nil
+}
+
+func (s *Storage) Add(address, password, accountID, nickname string) (string, error) {
+	var key string
+	err := s.update(func(accts map[string]Account) error {
+		key = nickname
+		if strings.TrimSpace(key) == "" {
+			// fallback: pakai address sebagai key unik
+			key = address
+		}
+		// jika key sudah ada & email beda, beri suffix
+		if _, ok := accts[key]; ok && accts[key].Address != address {
+			for i := 1; ; i++ {
+				k2 := fmt.Sprintf("%s_%d", key, i)
+				if _, clash := accts[k2]; !clash {
+					key = k2
+					break
+				}
+			}
+		}
+		accts[key] = Account{
+			Address:   address,
+			Password:  password,
+			AccountID: accountID,
+			Nickname:  nickname,
+		}
+		return nil
+	})
+	if err != nil {
 		return "", err
 	}
 	return key, nil
 }
 
+// modify mengubah satu akun yang sudah ada lewat updateWith.
+func (s *Storage) modify(key string, backup bool, fn func(acc *Account)) error {
+	return s.updateWith(backup, func(accts map[string]Account) error {
+		acc, ok := accts[key]
+		if !ok {
+			return fmt.Errorf("account key not found: %s", key)
+		}
+		fn(&acc)
+		accts[key] = acc
+		return nil
+	})
+}
+
+// SetToken menyimpan JWT terakhir agar akun bisa dibuka tanpa login ulang.
+// Tanpa rotasi backup: perintah yang membuka banyak akun memperbarui banyak
+// token sekaligus dan akan menggeser semua backup yang berarti.
+func (s *Storage) SetToken(key, token string, exp time.Time) error {
+	return s.modify(key, false, func(acc *Account) {
+		acc.Token = token
+		acc.TokenExp = 0
+		if !exp.IsZero() {
+			acc.TokenExp = exp.Unix()
+		}
+	})
+}
+
+func (s *Storage) SetNickname(key, nickname string) error {
+	return s.modify(key, true, func(acc *Account) {
+		acc.Nickname = nickname
+	})
+}
+
+// SetTags menambah (add) atau menghapus tag akun. Tag disimpan huruf kecil,
+// unik dan terurut.
+func (s *Storage) SetTags(key string, add bool, tags ...string) error {
+	return s.modify(key, true, func(acc *Account) {
+		for _, t := range tags {
+			t = normalizeTag(t)
+			if t == "" {
+				continue
+			}
+			if add {
+				acc.Tags = append(acc.Tags, t)
+			} else {
+				acc.Tags = slices.DeleteFunc(acc.Tags, func(x string) bool { return x == t })
+			}
+		}
+		slices.Sort(acc.Tags)
+		acc.Tags = slices.Compact(acc.Tags)
+		if len(acc.Tags) == 0 {
+			acc.Tags = nil
+		}
+	})
+}
+
 func (s *Storage) Get(key string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	acc, ok := s.Accounts[key]
 	return acc, ok
 }
 
+// Remove menghapus akun dan melaporkan apakah key ada. Keberadaannya
+// diperiksa pada isi file terbaru, sehingga akun yang baru ditambahkan proses
+// lain juga bisa dihapus.
 func (s *Storage) Remove(key string) bool {
-	if _, ok := s.Accounts[key]; !ok {
-		return false
-	}
-	delete(s.Accounts, key)
-	_ = s.Save()
-	return true
+	err := s.update(func(accts map[string]Account) error {
+		if _, ok := accts[key]; !ok {
+			return fmt.Errorf("account key not found: %s", key)
+		}
+		delete(accts, key)
+		return nil
+	})
+	return err == nil
 }
 
+// All mengembalikan map akun saat ini. Map itu tidak diubah lagi (update
+// selalu membuat map baru), jadi aman dibaca walau ada penulis lain.
 func (s *Storage) All() map[string]Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.Accounts
 }
 
 // optional: migrasi dari email_account.json (format lama) seperti di Python :contentReference[oaicite:7]{index=7}
-func (s *Storage) MigrateFromSingle() bool {
-	old := "email_account.json"
+func (s *Storage) MigrateFromSingle(old string) bool {
 	b, err := os.ReadFile(old)
 	if err != nil {
 		return false
@@ -146,103 +493,74 @@ func (s *Storage) MigrateFromSingle() bool {
 
 // ========================= Mail.tm Client =========================
 
+// Client menggabungkan klien API mailtm dengan akun di penyimpanan lokal.
 type Client struct {
-	BaseURL   string
-	Token     string
-	AccountID string
-	Address   string
-	Password  string
-	Domain    string
-
+	*mailtm.Client
+	Domain     string // domain akun baru: nama domain, "random", atau kosong
 	AccountKey string
 	Store      *Storage
-	HTTP       *http.Client
 }
 
+const userAgent = "Mail.TM-CLI/1.0 (+https://github.com/luzyver/Mail.TM-CLI)"
+
+// sharedHTTP dipakai semua Client dalam satu proses agar limiter request
+// berlaku untuk seluruh akun sekaligus.
+var sharedHTTP = sync.OnceValue(func() mailtm.HTTPDoer {
+	return &http.Client{Transport: mailtm.NewTransport(nil, cfg.RateLimit, cfg.Retries)}
+})
+
 func NewClient(accountKey string, storageFile string) *Client {
-	c := &Client{
-		BaseURL: "https://api.mail.tm",
-		Store:   NewStorage(storageFile),
-		HTTP: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+	c := newClient(NewStorage(storageFile), sharedHTTP())
 	if accountKey != "" {
-		_ = c.LoadAccount(accountKey)
+		_ = c.LoadAccount(context.Background(), accountKey)
 	}
 	return c
 }
 
-type domainResp struct {
-	Domain string `json:"domain"`
-}
-
-type hydraDomains struct {
-	Members []domainResp `json:"hydra:member"`
-}
-
-func (c *Client) GetDomains() ([]domainResp, error) {
-	req, _ := http.NewRequest("GET", c.BaseURL+"/domains", nil)
-	res, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		body, _ := io.ReadAll(res.Body)
-		return nil, fmt.Errorf("get domains failed: %s", string(body))
-	}
-	var hd hydraDomains
-	if err := json.NewDecoder(res.Body).Decode(&hd); err != nil {
-		return nil, err
+// newClient membuat Client yang memakai store dan doer yang sudah ada, agar
+// banyak akun bisa berbagi satu Storage.
+func newClient(store *Storage, doer mailtm.HTTPDoer) *Client {
+	c := &Client{
+		Client: mailtm.New(
+			mailtm.WithBaseURL(cfg.BaseURL),
+			mailtm.WithMercureURL(cfg.MercureURL),
+			mailtm.WithTimeout(time.Duration(cfg.HTTPTimeout)),
+			mailtm.WithUserAgent(userAgent),
+			mailtm.WithHTTPClient(doer),
+		),
+		Domain: cfg.DefaultDomain,
+		Store:  store,
+	}
+	c.OnToken = func(token string, exp time.Time) {
+		if c.AccountKey != "" && c.Store != nil {
+			_ = c.Store.SetToken(c.AccountKey, token, exp)
+		}
 	}
-	return hd.Members, nil
+	return c
 }
 
-func (c *Client) Register(username, password, nickname string, save bool) error {
-	doms, err := c.GetDomains()
+// Create mendaftarkan akun baru di c.Domain. Username dan password kosong
+// diisi acak; save menyimpan akun ke penyimpanan.
+func (c *Client) Create(ctx context.Context, username, password, nickname string, save bool) error {
+	doms, err := c.GetDomains(ctx)
 	if err != nil {
 		return err
 	}
-	if len(doms) == 0 {
-		return errors.New("no domains available")
+	if c.Domain, err = mailtm.PickDomain(doms, c.Domain); err != nil {
+		return err
 	}
-	c.Domain = doms[0].Domain
 
 	if strings.TrimSpace(username) == "" {
-		username = randomString(10)
+		if username, err = mailtm.GenerateUsername(mailtm.UsernameRandom, 10); err != nil {
+			return err
+		}
 	}
 	if strings.TrimSpace(password) == "" {
-		password = randomPassword(12)
-	}
-	c.Address = username + "@" + c.Domain
-	c.Password = password
-
-	body := map[string]string{
-		"address":  c.Address,
-		"password": c.Password,
-	}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", c.BaseURL+"/accounts", bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	res, err := c.HTTP.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	if res.StatusCode != 201 {
-		all, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("register failed: %s", string(all))
-	}
-	var acc struct {
-		ID string `json:"id"`
-	}
-	if err := json.NewDecoder(res.Body).Decode(&acc); err != nil {
-		return err
+		if password, err = mailtm.DefaultPasswordPolicy.Generate(); err != nil {
+			return err
+		}
 	}
-	c.AccountID = acc.ID
-
-	if _, err := c.GetToken(); err != nil {
+	if _, err := c.Register(ctx, username+"@"+c.Domain, password); err != nil {
 		return err
 	}
 	if save {
@@ -255,211 +573,112 @@ func (c *Client) Register(username, password, nickname string, save bool) error
 	return nil
 }
 
-func (c *Client) GetToken() (string, error) {
-	body := map[string]string{
-		"address":  c.Address,
-		"password": c.Password,
-	}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", c.BaseURL+"/token", bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	res, err := c.HTTP.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		all, _ := io.ReadAll(res.Body)
-		return "", fmt.Errorf("token failed: %s", string(all))
-	}
-	var tk struct {
-		Token string `json:"token"`
-	}
-	if err := json.NewDecoder(res.Body).Decode(&tk); err != nil {
-		return "", err
-	}
-	c.Token = tk.Token
-	return c.Token, nil
-}
-
-func (c *Client) authReq(method, path string, body io.Reader) (*http.Request, error) {
-	if c.Token == "" {
-		return nil, errors.New("no token; please login first")
+// Delete menghapus akun di server, dan dari penyimpanan bila fromStore.
+func (c *Client) Delete(ctx context.Context, fromStore bool) error {
+	if err := c.DeleteAccount(ctx); err != nil {
+		return err
 	}
-	req, _ := http.NewRequest(method, c.BaseURL+path, body)
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	if fromStore && c.AccountKey != "" {
+		c.Store.Remove(c.AccountKey)
 	}
-	return req, nil
-}
-
-type fromObj struct {
-	Address string `json:"address"`
-}
-type message struct {
-	ID        string  `json:"id"`
-	From      fromObj `json:"from"`
-	Subject   string  `json:"subject"`
-	CreatedAt string  `json:"createdAt"`
-	HTML      any     `json:"html"` // string atau []string (API bisa variatif)
-	Text      string  `json:"text"`
-	Seen      bool    `json:"seen"`
-}
-
-type hydraMessages struct {
-	Members []message `json:"hydra:member"`
+	c.AccountKey = ""
+	return nil
 }
 
-func (c *Client) GetMessages() ([]message, error) {
-	req, err := c.authReq("GET", "/messages", nil)
-	if err != nil {
-		return nil, err
+func (c *Client) SaveAccount(nickname string) (string, error) {
+	if c.Address == "" || c.Password == "" {
+		return "", errors.New("no account to save")
 	}
-	res, err := c.HTTP.Do(req)
+	key, err := c.Store.Add(c.Address, c.Password, c.AccountID, nickname)
 	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		all, _ := io.ReadAll(res.Body)
-		return nil, fmt.Errorf("get messages failed: %s", string(all))
+		return "", err
 	}
-	var hm hydraMessages
-	if err := json.NewDecoder(res.Body).Decode(&hm); err != nil {
-		return nil, err
+	if c.Token != "" {
+		_ = c.Store.SetToken(key, c.Token, c.TokenExp)
 	}
-	// mail.tm mengurutkan terbaru dulu; pastikan saja
-	return hm.Members, nil
+	return key, nil
 }
 
-func (c *Client) GetMessage(id string) (*message, error) {
-	req, err := c.authReq("GET", "/messages/"+id, nil)
-	if err != nil {
-		return nil, err
-	}
-	res, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, err
+func (c *Client) LoadAccount(ctx context.Context, key string) error {
+	acc, ok := c.Store.Get(key)
+	if !ok {
+		return fmt.Errorf("account key not found: %s", key)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		all, _ := io.ReadAll(res.Body)
-		return nil, fmt.Errorf("get message failed: %s", string(all))
+	c.Address = acc.Address
+	c.Password = acc.Password
+	c.AccountID = acc.AccountID
+	c.AccountKey = key
+	c.Token = acc.Token
+	c.TokenExp = time.Time{}
+	if acc.TokenExp > 0 {
+		c.TokenExp = time.Unix(acc.TokenExp, 0)
 	}
-	var m message
-	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
-		return nil, err
+	if c.TokenValid() && !c.TokenExp.IsZero() {
+		return nil
 	}
-	return &m, nil
+	_, err := c.GetToken(ctx)
+	return err
 }
 
-func (c *Client) DeleteMessage(id string) error {
-	req, err := c.authReq("DELETE", "/messages/"+id, nil)
-	if err != nil {
-		return err
+// SaveAttachment mengunduh lampiran ke dir dengan nama yang sudah
+// disanitasi. Tanpa overwrite, nama yang sudah ada diberi akhiran " (n)".
+func (c *Client) SaveAttachment(ctx context.Context, a mailtm.Attachment, dir string, overwrite bool) (string, int64, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, err
 	}
-	res, err := c.HTTP.Do(req)
+	body, err := c.DownloadAttachment(ctx, a)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 204 {
-		all, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("delete message failed: %s", string(all))
-	}
-	return nil
+	defer body.Close()
+	return saveFile(body, dir, a.Filename, overwrite)
 }
 
-func (c *Client) DeleteAccount(deleteFromStorage bool) error {
-	if c.AccountID == "" || c.Token == "" {
-		return errors.New("no token/account id; please login first")
-	}
-	req, err := c.authReq("DELETE", "/accounts/"+c.AccountID, nil)
-	if err != nil {
-		return err
+// saveFile menulis r ke dir dengan nama yang sudah disanitasi, dengan aturan
+// nama yang sama seperti SaveAttachment.
+func saveFile(r io.Reader, dir, filename string, overwrite bool) (string, int64, error) {
+	name := mailtm.SafeFilename(filename)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	var out *os.File
+	var err error
+	path := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		out, err = os.OpenFile(path, flag, 0644)
+		if !errors.Is(err, fs.ErrExist) || i > 999 {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
 	}
-	res, err := c.HTTP.Do(req)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 204 {
-		all, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("delete account failed: %s", string(all))
+	n, err := io.Copy(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
 	}
-	if deleteFromStorage && c.AccountKey != "" {
-		c.Store.Remove(c.AccountKey)
-	}
-	c.Token = ""
-	c.AccountID = ""
-	c.Address = ""
-	c.Password = ""
-	c.AccountKey = ""
-	return nil
-}
-
-func (c *Client) WaitForMessage(timeout, interval time.Duration) (*message, error) {
-	start := time.Now()
-	msgs, err := c.GetMessages()
 	if err != nil {
-		return nil, err
-	}
-	last := len(msgs)
-	for time.Since(start) < timeout {
-		time.Sleep(interval)
-		cur, err := c.GetMessages()
-		if err != nil {
-			return nil, err
-		}
-		if len(cur) > last {
-			// ambil paling baru
-			return &cur[0], nil
-		}
-		last = len(cur)
-	}
-	return nil, nil // timeout
-}
-
-func (c *Client) SaveAccount(nickname string) (string, error) {
-	if c.Address == "" || c.Password == "" {
-		return "", errors.New("no account to save")
-	}
-	return c.Store.Add(c.Address, c.Password, c.AccountID, nickname)
-}
-
-func (c *Client) LoadAccount(key string) error {
-	acc, ok := c.Store.Get(key)
-	if !ok {
-		return fmt.Errorf("account key not found: %s", key)
+		os.Remove(path) // jangan tinggalkan file setengah jadi
+		return "", 0, err
 	}
-	c.Address = acc.Address
-	c.Password = acc.Password
-	c.AccountID = acc.AccountID
-	c.AccountKey = key
-	_, err := c.GetToken()
-	return err
+	return path, n, nil
 }
 
 // ========================= Utils =========================
 
-func randomString(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
-	var b strings.Builder
-	for i := 0; i < n; i++ {
-		b.WriteByte(letters[time.Now().UnixNano()%int64(len(letters))])
+// humanSize menampilkan ukuran byte dalam B/KB/MB.
+func humanSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
 	}
-	return b.String()
-}
-
-func randomPassword(length int) string {
-	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
-	var b strings.Builder
-	for i := 0; i < length; i++ {
-		b.WriteByte(chars[time.Now().UnixNano()%int64(len(chars))])
-		time.Sleep(time.Nanosecond) // ensure different seeds
-	}
-	return b.String()
+	return fmt.Sprintf("%d B", n)
 }
 
 func clearScreen() {
@@ -501,14 +720,19 @@ func openInBrowser(html string) error {
 	if err := os.WriteFile(path, []byte(html), 0644); err != nil {
 		return err
 	}
+	return openURL("file:///" + filepath.ToSlash(strings.TrimPrefix(path, "/")))
+}
+
+// openURL membuka URL dengan aplikasi bawaan sistem.
+func openURL(u string) error {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", "file:///"+path)
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
 	case "darwin":
-		cmd = exec.Command("open", path)
+		cmd = exec.Command("open", u)
 	default:
-		cmd = exec.Command("xdg-open", path)
+		cmd = exec.Command("xdg-open", u)
 	}
 	return cmd.Start()
 }
@@ -524,6 +748,10 @@ func printAccounts(store *Storage) []string {
 		fmt.Println("\nTidak ada akun tersimpan.")
 		return keys
 	}
+	fmt.Println("\nMengambil ringkasan kotak masuk...")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Duration(cfg.HTTPTimeout))
+	defer cancel()
+	stats := dashboardCache.get(ctx, store, keys)
 	fmt.Println("\nDaftar akun tersimpan:")
 	for i, k := range keys {
 		a := accts[k]
@@ -532,6 +760,14 @@ func printAccounts(store *Storage) []string {
 			n = "Tanpa nama"
 		}
 		fmt.Printf("%d. %s (%s)\n", i+1, a.Address, n)
+		switch st := stats[k]; {
+		case st.Err != nil:
+			fmt.Printf("   Gagal memuat: %s\n", describeError(st.Err))
+		case st.Total == 0:
+			fmt.Println("   Kotak masuk kosong")
+		default:
+			fmt.Printf("   %d pesan, %s belum dibaca | terakhir %s: %s\n", st.Total, st.unreadLabel(), ago(st.LastAt), st.LastSubject)
+		}
 	}
 	return keys
 }
@@ -570,11 +806,14 @@ func selectAccount(store *Storage) (string, bool) {
 }
 
 func useAccount(store *Storage, key string) {
+	// isi kotak masuk bisa berubah selama akun dipakai
+	defer dashboardCache.invalidate(key)
 	header()
 	fmt.Println("MENGGUNAKAN AKUN EMAIL")
 	fmt.Println(strings.Repeat("-", 50))
 
-	client := NewClient(key, "email_accounts.json")
+	ctx := context.Background()
+	client := NewClient(key, cfg.StorePath)
 	if client.Address == "" {
 		fmt.Println("\nGagal memuat akun.")
 		pause()
@@ -586,7 +825,7 @@ func useAccount(store *Storage, key string) {
 	if strings.TrimSpace(nick) == "" {
 		nick = "Tanpa nama"
 	}
-	fmt.Printf("\nAkun: %s\nEmail: %s\nPassword: %s\n", nick, client.Address, client.Password)
+	fmt.Printf("\nAkun: %s\nEmail: %s\nPassword: %s\n", nick, client.Address, strings.Repeat("*", 8))
 
 	reader := bufio.NewReader(os.Stdin)
 	for {
@@ -596,139 +835,60 @@ func useAccount(store *Storage, key string) {
 		fmt.Println("3. Hapus semua pesan")
 		fmt.Println("4. Ubah nickname akun")
 		fmt.Println("5. Kembali ke menu utama")
-		fmt.Print("\nPilih operasi (1-5): ")
+		fmt.Println("6. Tandai pesan dibaca/belum dibaca")
+		fmt.Print("\nPilih operasi (1-6): ")
 		line, _ := reader.ReadString('\n')
 		line = strings.TrimSpace(line)
 
 		switch line {
 		case "1":
-			msgs, err := client.GetMessages()
-			if err != nil {
-				fmt.Println("\nError:", err)
-				pause()
-				continue
-			}
-			if len(msgs) == 0 {
-				fmt.Println("\nTidak ada pesan dalam kotak masuk.")
-				pause()
-				continue
-			}
-			fmt.Printf("\nDitemukan %d pesan:\n", len(msgs))
-			for i, m := range msgs {
-				from := m.From.Address
-				if from == "" {
-					from = "Unknown"
-				}
-				fmt.Printf("%d. Dari: %s\n   Subjek: %s\n   Tanggal: %s\n\n", i+1, from, nz(m.Subject, "No Subject"), nz(m.CreatedAt, "Unknown"))
-			}
-			fmt.Print("Masukkan nomor pesan untuk melihat detail (0 untuk batal): ")
-			sel, _ := reader.ReadString('\n')
-			sel = strings.TrimSpace(sel)
-			if sel == "0" || sel == "" {
-				continue
-			}
-			var idx int
-			fmt.Sscanf(sel, "%d", &idx)
-			if idx < 1 || idx > len(msgs) {
+			id, ok := selectMessage(ctx, client, reader)
+			if !ok {
 				continue
 			}
-			det, err := client.GetMessage(msgs[idx-1].ID)
+			det, err := client.GetMessage(ctx, id)
 			if err != nil {
-				fmt.Println("\nError:", err)
+				fmt.Println("\nError:", describeError(err))
 				pause()
 				continue
 			}
-			fmt.Println("\nDETAIL PESAN:")
-			fmt.Println("Dari:", nz(det.From.Address, "Unknown"))
-			fmt.Println("Subjek:", nz(det.Subject, "No Subject"))
-
-			// cek HTML
-			htmlStr := extractHTML(det.HTML)
-			if htmlStr != "" {
-				fmt.Println("\nPesan ini memiliki konten HTML.")
-				fmt.Println("1. Lihat teks biasa")
-				fmt.Println("2. Lihat HTML di browser")
-				opt := readLine("Pilih opsi (1-2): ")
-				if strings.TrimSpace(opt) == "2" {
-					if err := openInBrowser(htmlStr); err != nil {
-						fmt.Println("Gagal membuka browser:", err)
-					}
-				} else {
-					fmt.Println("\nIsi:", nz(det.Text, htmlStr))
-				}
-			} else {
-				fmt.Println("\nIsi:", nz(det.Text, "No Content"))
-			}
-			pause()
+			showMessage(ctx, client, "DETAIL PESAN", det)
 
 		case "2":
-			to := readLine("Masukkan timeout dalam detik (default: 30): ")
-			timeout := 30
+			def := int(time.Duration(cfg.WaitTimeout).Seconds())
+			to := readLine(fmt.Sprintf("Masukkan timeout dalam detik (default: %d): ", def))
+			timeout := def
 			fmt.Sscanf(to, "%d", &timeout)
 			if timeout <= 0 {
-				timeout = 30
+				timeout = def
 			}
 			fmt.Printf("\nMenunggu pesan baru untuk %s...\n(Ctrl+C untuk batalkan di terminal)\n", client.Address)
-			msg, err := client.WaitForMessage(time.Duration(timeout)*time.Second, 5*time.Second)
-			if err != nil {
-				fmt.Println("\nError:", err)
-				pause()
-				continue
-			}
-			if msg == nil {
+			det, err := client.WaitForMessage(ctx, time.Duration(timeout)*time.Second, time.Duration(cfg.PollInterval), mailtm.WaitFilter{})
+			if errors.Is(err, mailtm.ErrTimeout) {
 				fmt.Println("\nTimeout: Tidak ada pesan baru diterima.")
 				pause()
 				continue
 			}
-			det, err := client.GetMessage(msg.ID)
 			if err != nil {
-				fmt.Println("\nError:", err)
+				fmt.Println("\nError:", describeError(err))
 				pause()
 				continue
 			}
-			fmt.Println("\nPESAN BARU DITERIMA:")
-			fmt.Println("Dari:", nz(det.From.Address, "Unknown"))
-			fmt.Println("Subjek:", nz(det.Subject, "No Subject"))
-			htmlStr := extractHTML(det.HTML)
-			if htmlStr != "" {
-				fmt.Println("\nPesan ini memiliki konten HTML.")
-				fmt.Println("1. Lihat teks biasa")
-				fmt.Println("2. Lihat HTML di browser")
-				opt := readLine("Pilih opsi (1-2): ")
-				if strings.TrimSpace(opt) == "2" {
-					if err := openInBrowser(htmlStr); err != nil {
-						fmt.Println("Gagal membuka browser:", err)
-					}
-				} else {
-					fmt.Println("\nIsi:", nz(det.Text, htmlStr))
-				}
-			} else {
-				fmt.Println("\nIsi:", nz(det.Text, "No Content"))
-			}
-			pause()
+			showMessage(ctx, client, "PESAN BARU DITERIMA", det)
 
 		case "3":
 			yn := strings.ToLower(readLine("Apakah Anda yakin ingin menghapus semua pesan? (y/n): "))
 			if yn != "y" {
 				continue
 			}
-			msgs, err := client.GetMessages()
+			cnt, err := client.DeleteAllMessages(ctx)
 			if err != nil {
-				fmt.Println("\nError:", err)
-				pause()
-				continue
-			}
-			if len(msgs) == 0 {
+				fmt.Println("\nError:", describeError(err))
+			} else if cnt == 0 {
 				fmt.Println("\nTidak ada pesan untuk dihapus.")
 				pause()
 				continue
 			}
-			cnt := 0
-			for _, m := range msgs {
-				if err := client.DeleteMessage(m.ID); err == nil {
-					cnt++
-				}
-			}
 			fmt.Printf("\n%d pesan berhasil dihapus.\n", cnt)
 			pause()
 
@@ -739,14 +899,20 @@ func useAccount(store *Storage, key string) {
 			if strings.TrimSpace(newNick) == "" {
 				continue
 			}
-			acc.Nickname = newNick
-			store.Accounts[key] = acc
-			_ = store.Save()
+			if err := store.SetNickname(key, newNick); err != nil {
+				fmt.Println("\nGagal menyimpan nickname:", err)
+				pause()
+				continue
+			}
 			fmt.Println("\nNickname berhasil diubah menjadi:", newNick)
 			pause()
 
 		case "5":
 			return
+
+		case "6":
+			markMessages(ctx, client, reader)
+
 		default:
 			fmt.Println("\nPilihan tidak valid. Silakan coba lagi.")
 			time.Sleep(time.Second)
@@ -754,17 +920,382 @@ func useAccount(store *Storage, key string) {
 	}
 }
 
+// showMessage menampilkan detail pesan beserta pilihan tampilan HTML, daftar
+// tautan dan lampirannya.
+func showMessage(ctx context.Context, client *Client, title string, det *mailtm.Message) {
+	if !det.Seen {
+		// gagal menandai tidak menghalangi membaca pesan
+		if err := client.MarkSeen(ctx, det.ID, true); err == nil {
+			det.Seen = true
+		}
+	}
+	fmt.Printf("\n%s:\n", title)
+	fmt.Println("Dari:", nz(det.From.Address, "Unknown"))
+	fmt.Println("Subjek:", nz(det.Subject, "No Subject"))
+	printCode(os.Stdout, det)
+	printAttachments(os.Stdout, det)
+
+	// cek HTML
+	htmlStr := det.HTML.String()
+	if htmlStr != "" {
+		fmt.Println("\nPesan ini memiliki konten HTML.")
+		fmt.Println("1. Lihat teks biasa")
+		fmt.Println("2. Lihat HTML di browser")
+		opt := readLine("Pilih opsi (1-2): ")
+		if strings.TrimSpace(opt) == "2" {
+			if err := openInBrowser(htmlStr); err != nil {
+				fmt.Println("Gagal membuka browser:", err)
+			}
+		} else {
+			fmt.Println("\nIsi:", nz(det.Text, htmlStr))
+		}
+	} else {
+		fmt.Println("\nIsi:", nz(det.Text, "No Content"))
+	}
+
+	if links := mailtm.ExtractLinks(det); len(links) > 0 {
+		selectLink(links)
+	}
+	if len(det.Attachments) > 0 {
+		downloadAttachments(ctx, client, det.Attachments)
+	}
+	pause()
+}
+
+// printAttachments menampilkan daftar lampiran bernomor, jika ada.
+func printAttachments(w io.Writer, m *mailtm.Message) {
+	if len(m.Attachments) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Lampiran (%d):\n", len(m.Attachments))
+	for i, a := range m.Attachments {
+		fmt.Fprintf(w, "  %d. %s (%s, %s)\n", i+1, nz(a.Filename, "tanpa nama"), nz(a.ContentType, "unknown"), humanSize(int64(a.Size)))
+	}
+}
+
+// downloadAttachments menanyakan lampiran mana yang diunduh dan ke folder mana.
+func downloadAttachments(ctx context.Context, client *Client, atts []mailtm.Attachment) {
+	choice := strings.TrimSpace(readLine(fmt.Sprintf("\nUnduh lampiran nomor (1-%d, a untuk semua, Enter untuk lewati): ", len(atts))))
+	if choice == "" {
+		return
+	}
+	var pick []mailtm.Attachment
+	if strings.EqualFold(choice, "a") {
+		pick = atts
+	} else {
+		idx := 0
+		fmt.Sscanf(choice, "%d", &idx)
+		if idx < 1 || idx > len(atts) {
+			fmt.Println("Pilihan tidak valid.")
+			return
+		}
+		pick = atts[idx-1 : idx]
+	}
+	dir := strings.TrimSpace(readLine("Folder tujuan (default: folder saat ini): "))
+	if dir == "" {
+		dir = "."
+	}
+	for _, a := range pick {
+		path, n, err := client.SaveAttachment(ctx, a, dir, false)
+		if err != nil {
+			fmt.Printf("Gagal mengunduh %s: %s\n", nz(a.Filename, a.ID), describeError(err))
+			continue
+		}
+		fmt.Printf("Tersimpan: %s (%s)\n", path, humanSize(n))
+	}
+}
+
+// selectLink menampilkan tautan bernomor dan membuka yang dipilih di browser.
+func selectLink(links []mailtm.Link) {
+	fmt.Printf("\nTAUTAN (%d):\n", len(links))
+	for i, l := range links {
+		if l.Text != "" && l.Text != l.URL {
+			fmt.Printf("%d. %s\n   %s\n", i+1, l.Text, l.URL)
+		} else {
+			fmt.Printf("%d. %s\n", i+1, l.URL)
+		}
+	}
+	choice := readLine(fmt.Sprintf("\nBuka tautan nomor (1-%d, Enter untuk lewati): ", len(links)))
+	if strings.TrimSpace(choice) == "" {
+		return
+	}
+	idx := 0
+	fmt.Sscanf(choice, "%d", &idx)
+	if idx < 1 || idx > len(links) {
+		fmt.Println("Pilihan tidak valid.")
+		return
+	}
+	if err := openURL(links[idx-1].URL); err != nil {
+		fmt.Println("Gagal membuka browser:", err)
+	}
+}
+
+// watchAllMenu menampilkan feed pesan baru dari semua akun (atau yang
+// ber-tag tertentu) sampai Ctrl+C, lalu kembali ke menu.
+func watchAllMenu(store *Storage) {
+	header()
+	fmt.Println("PANTAU SEMUA AKUN")
+	fmt.Println(strings.Repeat("-", 50))
+	tags := splitTags(readLine("\nTag yang dipantau (pisahkan koma, kosongkan untuk semua akun): "))
+	keys := selectAccountKeys(store, tags)
+	if len(keys) == 0 {
+		fmt.Println("\nTidak ada akun yang cocok.")
+		pause()
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("\nMemantau %d akun... (Ctrl+C untuk kembali ke menu)\n\n", len(keys))
+	err := watchAccounts(ctx, store, keys, watchOptions{Interval: time.Duration(cfg.PollInterval), Parallel: 4}, func(ev feedEvent) {
+		if ev.Err != nil {
+			fmt.Printf("[%s] Error: %s\n", ev.Label, describeError(ev.Err))
+			return
+		}
+		m := ev.Message
+		fmt.Printf("[%s] %s | Dari: %s | Subjek: %s\n", ev.Label, nz(m.CreatedAt, "Unknown"), nz(m.From.Address, "Unknown"), nz(m.Subject, "No Subject"))
+	})
+	if err != nil {
+		fmt.Println("\nSemua akun gagal dipantau:", describeError(err))
+	}
+	pause()
+}
+
+// searchMenu mengambil pesan semua akun ke memori sekali dan mengindeksnya
+// bersama isi arsip, lalu menjawab query berulang kali dari indeks yang sama
+// sampai pengguna kembali. Arsip hanya dibaca, tidak diperbarui.
+func searchMenu(store *Storage) {
+	header()
+	fmt.Println("CARI PESAN")
+	fmt.Println(strings.Repeat("-", 50))
+	// pesan dari server hanya disimpan di memori; arsip tidak diubah
+	ar, err := openArchive(true)
+	if err != nil {
+		fmt.Println("\nGagal membuka arsip:", err)
+		pause()
+		return
+	}
+	defer ar.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	fmt.Println("\nMengambil pesan dari semua akun... (Ctrl+C untuk lewati)")
+	live, errs := fetchMessages(ctx, ar, store, selectAccountKeys(store, nil), searchQuery{})
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			fmt.Println("  Gagal:", err)
+		}
+	}
+	stop()
+	ix, err := buildSearchIndex(ar, live)
+	if err != nil {
+		fmt.Println("\nGagal membaca arsip:", err)
+		pause()
+		return
+	}
+	fmt.Printf("%d pesan terindeks.\n", ix.Len())
+	fmt.Println("\nContoh: invoice from:stripe after:7d")
+	fmt.Println("        subject:\"reset password\" account:kerja -newsletter")
+	fmt.Println("Filter: from:, subject:, account:, before:, after: (YYYY-MM-DD atau 7d)")
+
+	for {
+		line := strings.TrimSpace(readLine("\nCari (kosongkan untuk kembali): "))
+		if line == "" {
+			return
+		}
+		q, err := parseSearchQuery(line)
+		if err != nil {
+			fmt.Println("Query tidak valid:", err)
+			continue
+		}
+		hits := ix.Search(q)
+		if len(hits) == 0 {
+			fmt.Println("Tidak ada pesan yang cocok.")
+			continue
+		}
+		const shown = 20
+		fmt.Printf("\n%d pesan cocok", len(hits))
+		if len(hits) > shown {
+			fmt.Printf(", menampilkan %d teratas", shown)
+			hits = hits[:shown]
+		}
+		fmt.Println(":")
+		for i, h := range hits {
+			m := h.Doc.Msg
+			fmt.Printf("%d. [%s] %s | Dari: %s | Subjek: %s\n", i+1, h.Doc.Account, ago(h.Doc.At), nz(m.From.Address, "Unknown"), nz(m.Subject, "No Subject"))
+			if s := h.snippet(q, 100); s != "" {
+				fmt.Println("   " + s)
+			}
+		}
+		choice := readLine(fmt.Sprintf("\nBuka pesan nomor (1-%d, Enter untuk cari lagi): ", len(hits)))
+		idx := 0
+		fmt.Sscanf(choice, "%d", &idx)
+		if idx < 1 || idx > len(hits) {
+			continue
+		}
+		h := hits[idx-1]
+		fmt.Printf("\nPESAN ARSIP (%s):\n", h.Doc.Account)
+		printMessage(h.Doc.Msg, false)
+		pause()
+	}
+}
+
+// markMessages menandai satu atau semua pesan sudah/belum dibaca.
+func markMessages(ctx context.Context, client *Client, reader *bufio.Reader) {
+	fmt.Println("\nTANDAI PESAN:")
+	fmt.Println("1. Satu pesan sudah dibaca")
+	fmt.Println("2. Satu pesan belum dibaca")
+	fmt.Println("3. Semua pesan sudah dibaca")
+	fmt.Println("4. Semua pesan belum dibaca")
+	opt := strings.TrimSpace(readLine("Pilih opsi (1-4, Enter untuk batal): "))
+	seen := opt == "1" || opt == "3"
+	state := "belum dibaca"
+	if seen {
+		state = "sudah dibaca"
+	}
+	switch opt {
+	case "1", "2":
+		id, ok := selectMessage(ctx, client, reader)
+		if !ok {
+			return
+		}
+		if err := client.MarkSeen(ctx, id, seen); err != nil {
+			fmt.Println("\nError:", describeError(err))
+		} else {
+			fmt.Println("\nPesan ditandai " + state + ".")
+		}
+	case "3", "4":
+		cnt, err := client.MarkAllSeen(ctx, seen)
+		if err != nil {
+			fmt.Println("\nError:", describeError(err))
+		}
+		fmt.Printf("\n%d pesan ditandai %s.\n", cnt, state)
+	default:
+		return
+	}
+	pause()
+}
+
+// selectMessage menampilkan kotak masuk per halaman dan mengembalikan ID pesan
+// yang dipilih.
+func selectMessage(ctx context.Context, client *Client, reader *bufio.Reader) (string, bool) {
+	page := 1
+	for {
+		p, err := client.GetMessagesPage(ctx, page)
+		if err != nil {
+			fmt.Println("\nError:", describeError(err))
+			pause()
+			return "", false
+		}
+		if len(p.Messages) == 0 && page == 1 {
+			fmt.Println("\nTidak ada pesan dalam kotak masuk.")
+			pause()
+			return "", false
+		}
+		unseen := 0
+		for _, m := range p.Messages {
+			if !m.Seen {
+				unseen++
+			}
+		}
+		fmt.Printf("\nDitemukan %d pesan, %d belum dibaca di halaman ini (halaman %d/%d):\n", max(p.TotalItems, len(p.Messages)), unseen, p.Page, p.Last)
+		for i, m := range p.Messages {
+			from := m.From.Address
+			if from == "" {
+				from = "Unknown"
+			}
+			mark := ""
+			if !m.Seen {
+				mark = " [BARU]"
+			}
+			fmt.Printf("%d.%s Dari: %s\n   Subjek: %s\n   Tanggal: %s\n\n", i+1, mark, from, nz(m.Subject, "No Subject"), nz(m.CreatedAt, "Unknown"))
+		}
+		prompt := "Masukkan nomor pesan untuk melihat detail"
+		if p.Next > 0 {
+			prompt += ", n untuk halaman berikutnya"
+		}
+		if page > 1 {
+			prompt += ", p untuk halaman sebelumnya"
+		}
+		fmt.Print(prompt + " (0 untuk batal): ")
+		sel, _ := reader.ReadString('\n')
+		sel = strings.ToLower(strings.TrimSpace(sel))
+		switch {
+		case sel == "n" && p.Next > 0:
+			page = p.Next
+			continue
+		case sel == "p" && page > 1:
+			page--
+			continue
+		case sel == "0" || sel == "":
+			return "", false
+		}
+		var idx int
+		fmt.Sscanf(sel, "%d", &idx)
+		if idx < 1 || idx > len(p.Messages) {
+			return "", false
+		}
+		return p.Messages[idx-1].ID, true
+	}
+}
+
 func createNewEmail(store *Storage) {
 	header()
 	fmt.Println("MEMBUAT EMAIL BARU")
 	fmt.Println(strings.Repeat("-", 50))
 
-	custom := readLine("\nMasukkan username kustom (kosongkan untuk username acak): ")
+	ctx := context.Background()
+	client := NewClient("", cfg.StorePath)
+	doms, err := client.GetDomains(ctx)
+	if err != nil {
+		fmt.Println("\nGagal mengambil daftar domain:", describeError(err))
+		pause()
+		return
+	}
+	var active []mailtm.Domain
+	for _, d := range doms {
+		if d.IsActive {
+			active = append(active, d)
+		}
+	}
+	if len(active) > 1 {
+		fmt.Println("\nDomain tersedia:")
+		for i, d := range active {
+			fmt.Printf("%d. %s\n", i+1, d.Domain)
+		}
+		def := nz(client.Domain, active[0].Domain)
+		sel := strings.ToLower(strings.TrimSpace(readLine(fmt.Sprintf("Pilih domain (1-%d, r = acak, kosong = %s): ", len(active), def))))
+		idx := 0
+		fmt.Sscanf(sel, "%d", &idx)
+		switch {
+		case sel == "r":
+			client.Domain = mailtm.DomainRandom
+		case idx >= 1 && idx <= len(active):
+			client.Domain = active[idx-1].Domain
+		}
+	}
+
+	custom := strings.TrimSpace(readLine("\nMasukkan username kustom (kosongkan untuk username acak): "))
+	if custom == "" {
+		fmt.Println("\nGaya username acak:")
+		fmt.Println("1. Acak (huruf & angka)")
+		fmt.Println("2. Mudah diucapkan")
+		fmt.Println("3. Gabungan kata")
+		style := mailtm.UsernameRandom
+		switch strings.TrimSpace(readLine("Pilih gaya (1-3, default 1): ")) {
+		case "2":
+			style = mailtm.UsernamePronounceable
+		case "3":
+			style = mailtm.UsernameWords
+		}
+		if custom, err = mailtm.GenerateUsername(style, 10); err != nil {
+			fmt.Println("\nGagal membuat username:", err)
+			pause()
+			return
+		}
+	}
 	nick := readLine("Masukkan nickname untuk akun ini: ")
 
-	client := NewClient("", "email_accounts.json")
-	if err := client.Register(strings.TrimSpace(custom), "", strings.TrimSpace(nick), true); err != nil {
-		fmt.Println("\nGagal membuat akun:", err)
+	if err := client.Create(ctx, custom, "", strings.TrimSpace(nick), true); err != nil {
+		fmt.Println("\nGagal membuat akun:", describeError(err))
 		pause()
 		return
 	}
@@ -778,6 +1309,7 @@ func deleteAccount(store *Storage) {
 	fmt.Println("MENGHAPUS AKUN EMAIL")
 	fmt.Println(strings.Repeat("-", 50))
 
+	ctx := context.Background()
 	key, ok := selectAccount(store)
 	if !ok {
 		return
@@ -790,10 +1322,21 @@ func deleteAccount(store *Storage) {
 		pause()
 		return
 	}
-	client := NewClient(key, "email_accounts.json")
+	client := NewClient(key, cfg.StorePath)
+	if client.Address != "" && strings.ToLower(readLine("Arsipkan semua pesan ke arsip lokal dulu? (y/n): ")) == "y" {
+		fmt.Println("\nMengarsipkan pesan...")
+		res, err := archiveBeforeDelete(ctx, client)
+		if err != nil {
+			fmt.Println("Gagal mengarsipkan:", describeError(err))
+			fmt.Println("Akun tidak dihapus.")
+			pause()
+			return
+		}
+		fmt.Printf("%d pesan baru diarsipkan (%d total). Baca lagi dengan 'mailtm archive list --account %s'.\n", res.New, res.Total, acc.Address)
+	}
 	if client.Address != "" {
-		if err := client.DeleteAccount(true); err != nil {
-			fmt.Println("\nGagal menghapus akun dari server:", err)
+		if err := client.Delete(ctx, true); err != nil {
+			fmt.Println("\nGagal menghapus akun dari server:", describeError(err))
 			fmt.Println("Menghapus dari penyimpanan lokal saja...")
 			store.Remove(key)
 			fmt.Printf("Akun %s berhasil dihapus dari penyimpanan lokal.\n", acc.Address)
@@ -824,12 +1367,20 @@ func showAbout() {
 }
 
 func mainMenu() {
-	store := NewStorage("email_accounts.json")
+	store := NewStorage(cfg.StorePath)
+	if err := store.Err(); err != nil {
+		header()
+		fmt.Println("PERINGATAN: file akun tidak bisa dibaca.")
+		fmt.Println(err)
+		fmt.Println("\nFile tidak akan ditimpa. Jalankan 'mailtm recover' untuk memulihkan akun")
+		fmt.Println("dari file yang rusak atau dari backup (.bak.N).")
+		pause()
+	}
 	// migrasi dari format lama jika ada (paritas dengan Python) :contentReference[oaicite:8]{index=8}
-	if _, err := os.Stat("email_account.json"); err == nil {
-		if _, err2 := os.Stat("email_accounts.json"); os.IsNotExist(err2) {
+	if old := legacyAccountFile(); old != "" {
+		if _, err2 := os.Stat(cfg.StorePath); os.IsNotExist(err2) {
 			fmt.Println("Terdeteksi format akun lama. Melakukan migrasi otomatis...")
-			if store.MigrateFromSingle() {
+			if store.MigrateFromSingle(old) {
 				fmt.Println("Migrasi selesai.")
 				time.Sleep(2 * time.Second)
 			}
@@ -851,7 +1402,9 @@ func mainMenu() {
 		fmt.Println("3. Hapus akun")
 		fmt.Println("4. Tentang aplikasi")
 		fmt.Println("5. Keluar")
-		fmt.Print("\nPilih menu (1-5): ")
+		fmt.Println("6. Pantau semua akun")
+		fmt.Println("7. Cari pesan")
+		fmt.Print("\nPilih menu (1-7): ")
 		line, _ := reader.ReadString('\n')
 		line = strings.TrimSpace(line)
 
@@ -870,6 +1423,10 @@ func mainMenu() {
 		case "5":
 			fmt.Println("\nTerima kasih telah menggunakan aplikasi Email Sementara Mail.TM!")
 			return
+		case "6":
+			watchAllMenu(store)
+		case "7":
+			searchMenu(store)
 		default:
 			fmt.Println("\nPilihan tidak valid. Silakan coba lagi.")
 			time.Sleep(time.Second)
@@ -878,6 +1435,13 @@ func mainMenu() {
 }
 
 func main() {
+	if err := loadConfig(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Println("\nTerjadi kesalahan:", r)
@@ -886,6 +1450,13 @@ func main() {
 	mainMenu()
 }
 
+// printCode menampilkan kode verifikasi yang terdeteksi, jika ada.
+func printCode(w io.Writer, m *mailtm.Message) {
+	if c, ok := mailtm.BestCode(m); ok {
+		fmt.Fprintln(w, "Kode terdeteksi:", c.Value)
+	}
+}
+
 func nz(s, def string) string {
 	if strings.TrimSpace(s) == "" {
 		return def
@@ -893,25 +1464,28 @@ func nz(s, def string) string {
 	return s
 }
 
-func extractHTML(html any) string {
-	switch v := html.(type) {
-	case string:
-		return v
-	case []any:
-		var parts []string
-		for _, it := range v {
-			if str, ok := it.(string); ok {
-				parts = append(parts, str)
-			}
-		}
-		return strings.Join(parts, "\n")
-	default:
-		// beberapa response bisa []string; marshal lalu coba parse
-		b, _ := json.Marshal(v)
-		var arr []string
-		if err := json.Unmarshal(b, &arr); err == nil {
-			return strings.Join(arr, "\n")
+// describeError menerjemahkan error menjadi pesan yang mudah dipahami di menu.
+func describeError(err error) string {
+	var apiErr *mailtm.APIError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, mailtm.ErrAddressTaken):
+		return "Alamat email sudah dipakai, coba username lain."
+	case errors.Is(err, mailtm.ErrRateLimited):
+		return "Terlalu banyak request ke Mail.tm, coba lagi sebentar lagi."
+	case errors.Is(err, mailtm.ErrUnauthorized):
+		return "Login gagal: email atau password tidak valid, atau akun sudah dihapus."
+	case errors.Is(err, mailtm.ErrNotFound):
+		return "Data tidak ditemukan di server (mungkin sudah dihapus)."
+	case errors.As(err, &apiErr) && apiErr.Status >= 500:
+		return fmt.Sprintf("Server Mail.tm sedang bermasalah (HTTP %d), coba lagi nanti.", apiErr.Status)
+	case errors.As(err, &apiErr) && len(apiErr.Violations) > 0:
+		parts := make([]string, len(apiErr.Violations))
+		for i, v := range apiErr.Violations {
+			parts[i] = v.PropertyPath + ": " + v.Message
 		}
+		return "Data ditolak server: " + strings.Join(parts, "; ")
 	}
-	return ""
+	return err.Error()
 }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+// ========================= Output =========================
+
+// outputVersion dinaikkan hanya jika bentuk JSON berubah secara tidak kompatibel.
+const outputVersion = 1
+
+type outputFormat string
+
+const (
+	outPlain  outputFormat = "plain"
+	outTable  outputFormat = "table"
+	outJSON   outputFormat = "json"
+	outNDJSON outputFormat = "ndjson"
+)
+
+func (o *outputFormat) String() string { return string(*o) }
+
+func (o *outputFormat) Set(v string) error {
+	switch f := outputFormat(strings.ToLower(strings.TrimSpace(v))); f {
+	case outPlain, outTable, outJSON, outNDJSON:
+		*o = f
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (json, ndjson, table, plain)", v)
+}
+
+func (o outputFormat) machine() bool { return o == outJSON || o == outNDJSON }
+
+var outFmt = outPlain
+
+type envelope struct {
+	Version int    `json:"version"`
+	Kind    string `json:"kind"`
+	Count   *int   `json:"count,omitempty"`
+	Data    any    `json:"data"`
+}
+
+type errorEnvelope struct {
+	Version int       `json:"version"`
+	Error   errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Command string `json:"command,omitempty"`
+	Message string `json:"message"`
+	Status  int    `json:"status,omitempty"` // status HTTP bila error berasal dari API
+}
+
+// bentuk JSON yang stabil; jangan langsung marshal struct API.
+
+type accountOut struct {
+	Key       string    `json:"key"`
+	Address   string    `json:"address"`
+	Nickname  string    `json:"nickname"`
+	AccountID string    `json:"account_id"`
+	Password  string    `json:"password,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Stats     *statsOut `json:"stats,omitempty"`
+}
+
+type statsOut struct {
+	Total       int    `json:"total"`
+	Unread      int    `json:"unread"`
+	UnreadMore  bool   `json:"unread_more,omitempty"` // unread hanya dari halaman pertama
+	LastSubject string `json:"last_subject,omitempty"`
+	LastAt      string `json:"last_at,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type messageOut struct {
+	Account   string   `json:"account,omitempty"` // alamat akun, diisi pada feed banyak akun
+	ID        string   `json:"id"`
+	From      string   `json:"from"`
+	Subject   string   `json:"subject"`
+	CreatedAt string   `json:"created_at"`
+	Seen      bool     `json:"seen"`
+	Text      string   `json:"text,omitempty"`
+	HTML      string   `json:"html,omitempty"`
+	Codes     []string `json:"codes,omitempty"` // kandidat kode verifikasi, terbaik dulu
+	Links     []string `json:"links,omitempty"`
+	// Attachments hanya diisi untuk detail pesan
+	Attachments []attachmentOut `json:"attachments,omitempty"`
+}
+
+type attachmentOut struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Path        string `json:"path,omitempty"` // diisi setelah disimpan
+}
+
+type linkOut struct {
+	URL      string `json:"url"`
+	Text     string `json:"text,omitempty"`
+	Original string `json:"original,omitempty"` // href asli sebelum redirect dibuka
+}
+
+type sourceOut struct {
+	ID   string `json:"id"`
+	Path string `json:"path,omitempty"` // diisi bila disimpan ke file
+	Data string `json:"data,omitempty"`
+}
+
+type headerOut struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type mimePartOut struct {
+	Path        string `json:"path"`
+	Depth       int    `json:"depth"`
+	ContentType string `json:"content_type"`
+	Charset     string `json:"charset,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Disposition string `json:"disposition,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	Size        int    `json:"size"` // ukuran setelah didekode
+	Body        string `json:"body,omitempty"`
+	BodyBase64  string `json:"body_base64,omitempty"` // untuk bagian non-teks
+}
+
+type mimeOut struct {
+	ID      string        `json:"id,omitempty"`
+	Headers []headerOut   `json:"headers"`
+	Parts   []mimePartOut `json:"parts"`
+}
+
+type archiveAccountOut struct {
+	Address   string   `json:"address"`
+	AccountID string   `json:"account_id"`
+	Nickname  string   `json:"nickname,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Messages  int      `json:"messages"`
+	LastSync  string   `json:"last_sync,omitempty"`
+	Stored    bool     `json:"stored"` // akun masih ada di penyimpanan akun
+}
+
+type archiveSyncOut struct {
+	Address     string `json:"address"`
+	New         int    `json:"new"`
+	Sources     int    `json:"sources"`
+	Attachments int    `json:"attachments"`
+	Total       int    `json:"total"`
+	Error       string `json:"error,omitempty"`
+}
+
+type searchHitOut struct {
+	messageOut
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+type domainOut struct {
+	ID        string `json:"id"`
+	Domain    string `json:"domain"`
+	Active    bool   `json:"active"`
+	Private   bool   `json:"private"`
+	CreatedAt string `json:"created_at"`
+}
+
+type resultOut struct {
+	Action  string `json:"action"`
+	Key     string `json:"key,omitempty"`
+	Address string `json:"address,omitempty"`
+	Count   int    `json:"count,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+func toAccountOut(key string, a Account, withPassword bool) accountOut {
+	o := accountOut{Key: key, Address: a.Address, Nickname: a.Nickname, AccountID: a.AccountID, Tags: a.Tags}
+	if withPassword {
+		o.Password = a.Password
+	}
+	return o
+}
+
+func toMessageOut(m *mailtm.Message, withBody bool) messageOut {
+	o := messageOut{
+		ID:        m.ID,
+		From:      m.From.Address,
+		Subject:   m.Subject,
+		CreatedAt: m.CreatedAt,
+		Seen:      m.Seen,
+	}
+	if withBody {
+		o.Text = m.Text
+		o.HTML = m.HTML.String()
+		o.Codes = messageCodes(m)
+		for _, l := range mailtm.ExtractLinks(m) {
+			o.Links = append(o.Links, l.URL)
+		}
+		for _, a := range m.Attachments {
+			o.Attachments = append(o.Attachments, toAttachmentOut(a))
+		}
+	}
+	return o
+}
+
+func toStatsOut(st accountStats) *statsOut {
+	if st.Err != nil {
+		return &statsOut{Error: st.Err.Error()}
+	}
+	o := &statsOut{Total: st.Total, Unread: st.Unread, UnreadMore: st.UnreadMore, LastSubject: st.LastSubject}
+	if !st.LastAt.IsZero() {
+		o.LastAt = st.LastAt.UTC().Format(time.RFC3339)
+	}
+	return o
+}
+
+func toAttachmentOut(a mailtm.Attachment) attachmentOut {
+	return attachmentOut{ID: a.ID, Filename: a.Filename, ContentType: a.ContentType, Size: int64(a.Size)}
+}
+
+func toMIMEPartOut(depth int, p *mailtm.Part) mimePartOut {
+	return mimePartOut{
+		Path:        p.Path,
+		Depth:       depth,
+		ContentType: p.ContentType,
+		Charset:     p.Params["charset"],
+		Encoding:    p.Encoding,
+		Disposition: p.Disposition,
+		Filename:    p.Filename,
+		Size:        len(p.Body),
+	}
+}
+
+// messageCodes mengembalikan kandidat kode verifikasi yang cukup meyakinkan.
+func messageCodes(m *mailtm.Message) []string {
+	var out []string
+	for _, c := range mailtm.ExtractCodes(m) {
+		if c.Score >= mailtm.MinCodeScore {
+			out = append(out, c.Value)
+		}
+	}
+	return out
+}
+
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	if outFmt == outJSON {
+		enc.SetIndent("", "  ")
+	}
+	_ = enc.Encode(v)
+}
+
+// emit mencetak satu objek; human dipakai untuk mode plain dan table.
+func emit(kind string, data any, human func(w io.Writer)) {
+	if outFmt.machine() {
+		writeJSON(os.Stdout, envelope{Version: outputVersion, Kind: kind, Data: data})
+		return
+	}
+	human(os.Stdout)
+}
+
+// emitList mencetak daftar. Mode plain menulis baris dipisah tab tanpa header,
+// mode table merapikan kolom dan menambahkan header.
+func emitList[T any](kind string, items []T, header []string, row func(T) []string) {
+	switch outFmt {
+	case outJSON:
+		n := len(items)
+		if items == nil {
+			items = []T{}
+		}
+		writeJSON(os.Stdout, envelope{Version: outputVersion, Kind: kind, Count: &n, Data: items})
+	case outNDJSON:
+		for _, it := range items {
+			writeJSON(os.Stdout, envelope{Version: outputVersion, Kind: kind, Data: it})
+		}
+	case outTable:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, it := range items {
+			fmt.Fprintln(tw, strings.Join(row(it), "\t"))
+		}
+		tw.Flush()
+	default:
+		for _, it := range items {
+			fmt.Println(strings.Join(row(it), "\t"))
+		}
+	}
+}
+
+func emitResult(r resultOut, human string) {
+	emit("result", r, func(w io.Writer) { fmt.Fprintln(w, human) })
+}
+
+func reportError(command string, err error) {
+	if outFmt.machine() {
+		body := errorBody{Command: command, Message: err.Error()}
+		var apiErr *mailtm.APIError
+		if errors.As(err, &apiErr) {
+			body.Status = apiErr.Status
+		}
+		enc := json.NewEncoder(os.Stderr)
+		_ = enc.Encode(errorEnvelope{Version: outputVersion, Error: body})
+		return
+	}
+	fmt.Fprintln(os.Stderr, "error:", err)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// ========================= Pemulihan penyimpanan =========================
+
+// entri akun berupa objek datar, jadi pasangan "key": {...} tetap bisa
+// ditemukan walau bagian lain file rusak atau terpotong.
+var accountEntryRe = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*(\{[^{}]*\})`)
+
+// salvageAccounts mengambil semua entri akun yang masih valid dari data JSON
+// yang rusak.
+func salvageAccounts(data []byte) map[string]Account {
+	out := map[string]Account{}
+	for _, m := range accountEntryRe.FindAllSubmatch(data, -1) {
+		var key string
+		if err := json.Unmarshal(append(append([]byte{'"'}, m[1]...), '"'), &key); err != nil {
+			continue
+		}
+		var acc Account
+		if err := json.Unmarshal(m[2], &acc); err != nil || acc.Address == "" || acc.Password == "" {
+			continue
+		}
+		out[key] = acc
+	}
+	return out
+}
+
+type recoverySource struct {
+	File     string
+	Accounts map[string]Account
+	Sealer   *sealer
+	Partial  bool // hasil salvage, bukan file yang terbaca utuh
+}
+
+// recoverFrom membaca file secara normal, lalu mencoba salvage jika gagal.
+func recoverFrom(file string) (*recoverySource, error) {
+	accts, sl, err := readStoreFile(file, nil)
+	if err == nil {
+		return &recoverySource{File: file, Accounts: accts, Sealer: sl}, nil
+	}
+	data, rerr := os.ReadFile(file)
+	if rerr != nil {
+		return nil, rerr
+	}
+	// file terenkripsi yang rusak tidak bisa di-salvage sebagian
+	if parseEncrypted(data) != nil {
+		return nil, err
+	}
+	salvaged := salvageAccounts(data)
+	if len(salvaged) == 0 {
+		return nil, err
+	}
+	return &recoverySource{File: file, Accounts: salvaged, Partial: true}, nil
+}
+
+// findRecovery mencoba file utama lalu backup dari yang terbaru.
+func findRecovery(file string) (*recoverySource, error) {
+	var errs []error
+	candidates := []string{file}
+	for i := 1; i <= storeBackups; i++ {
+		candidates = append(candidates, backupName(file, i))
+	}
+	for _, f := range candidates {
+		src, err := recoverFrom(f)
+		if err == nil {
+			return src, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%s and its backups do not exist", file)
+	}
+	return nil, fmt.Errorf("nothing could be recovered: %w", errors.Join(errs...))
+}
+
+func cmdRecover(ctx context.Context, args []string) error {
+	fs := newFlagSet("recover")
+	from := fs.String("from", "", "pulihkan dari file ini (mis. email_accounts.json.bak.2)")
+	dryRun := fs.Bool("dry-run", false, "hanya tampilkan akun yang bisa dipulihkan")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	file := cfg.StorePath
+	store := NewStorage(file)
+	if store.Err() == nil && *from == "" {
+		emitResult(resultOut{Action: "recover", Count: len(store.All()), Detail: "ok"},
+			fmt.Sprintf("File %s terbaca dengan baik (%d akun); tidak ada yang perlu dipulihkan.", file, len(store.All())))
+		return nil
+	}
+
+	var src *recoverySource
+	var err error
+	if *from != "" {
+		src, err = recoverFrom(*from)
+	} else {
+		src, err = findRecovery(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(src.Accounts))
+	for k := range src.Accounts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]accountOut, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, toAccountOut(k, src.Accounts[k], false))
+	}
+	if !outFmt.machine() {
+		how := "terbaca utuh"
+		if src.Partial {
+			how = "sebagian (salvage)"
+		}
+		fmt.Fprintf(os.Stderr, "Sumber: %s, %s, %d akun\n", src.File, how, len(items))
+	}
+	emitList("account", items, []string{"KEY", "EMAIL", "NICKNAME"}, func(a accountOut) []string {
+		return []string{a.Key, a.Address, nz(a.Nickname, "Tanpa nama")}
+	})
+	if *dryRun {
+		return nil
+	}
+
+	// simpan file yang rusak apa adanya sebelum ditimpa
+	if store.Err() != nil {
+		if err := copyFile(file, fmt.Sprintf("%s.corrupt-%s", file, time.Now().Format("20060102-150405"))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	store.loadErr = nil
+	store.Accounts = src.Accounts
+	store.sealer = src.Sealer
+	if err := store.Save(); err != nil {
+		return err
+	}
+	if !outFmt.machine() {
+		fmt.Fprintf(os.Stderr, "%d akun dipulihkan ke %s.\n", len(items), file)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
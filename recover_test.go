@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestStore menulis file akun biasa dengan addrs dan mengembalikan isinya.
+func writeTestStore(t *testing.T, file string, addrs ...string) []byte {
+	t.Helper()
+	s := NewStorage(file)
+	for _, a := range addrs {
+		if _, err := s.Add(a, "rahasia-"+a, "id-"+a, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSalvageTruncated(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	data := writeTestStore(t, file, "a@x.test", "b@x.test", "c@x.test")
+	// potong di tengah entri terakhir
+	cut := bytes.LastIndex(data, []byte(`"password"`))
+	if err := os.WriteFile(file, data[:cut], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := recoverFrom(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !src.Partial || len(src.Accounts) != 2 {
+		t.Fatalf("Partial = %v, accounts = %v", src.Partial, src.Accounts)
+	}
+	for _, acc := range src.Accounts {
+		if acc.Password != "rahasia-"+acc.Address || acc.AccountID != "id-"+acc.Address {
+			t.Fatalf("salvaged %+v", acc)
+		}
+	}
+}
+
+func TestFindRecoveryBackup(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	good := writeTestStore(t, file, "a@x.test", "b@x.test")
+	for name, data := range map[string][]byte{
+		file:                []byte("\x00\x00 rusak"),
+		backupName(file, 1): []byte(`{"accounts": {"x": {"address": "tanpa-password"`),
+		backupName(file, 2): good,
+		backupName(file, 3): good[:len(good)/2],
+	} {
+		if err := os.WriteFile(name, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := findRecovery(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.File != backupName(file, 2) || src.Partial || len(src.Accounts) != 2 {
+		t.Fatalf("File = %s, Partial = %v, accounts = %d", src.File, src.Partial, len(src.Accounts))
+	}
+}
+
+func TestCLIRecoverNothing(t *testing.T) {
+	newCLIFake(t)
+	file := os.Getenv("MAILTM_STORE")
+	bad := []byte(`{"accounts": {"a": {"address": "a@x.test", "pass`)
+	for _, name := range []string{file, backupName(file, 1)} {
+		if err := os.WriteFile(name, bad, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, code := runMT(t, "recover")
+	if code == 0 || !strings.Contains(out, "nothing could be recovered") {
+		t.Fatalf("exit %d: %s", code, out)
+	}
+	if got, _ := os.ReadFile(file); !bytes.Equal(got, bad) {
+		t.Fatalf("store was overwritten: %q", got)
+	}
+	if m, _ := filepath.Glob(file + ".corrupt-*"); len(m) != 0 {
+		t.Fatalf("unexpected copies %v", m)
+	}
+
+	// setelah backup yang utuh tersedia, recover menulis ulang file utama
+	good := writeTestStore(t, filepath.Join(t.TempDir(), "lain.json"), "a@x.test")
+	if err := os.WriteFile(backupName(file, 2), good, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runMT(t, "recover"); code != 0 {
+		t.Fatalf("exit %d: %s", code, out)
+	}
+	if s := NewStorage(file); s.Err() != nil || len(s.All()) != 1 {
+		t.Fatalf("after recover: err %v, %d accounts", s.Err(), len(s.All()))
+	}
+}
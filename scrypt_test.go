@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Vektor uji RFC 7914 §12. Vektor keempat (N = 2^20) butuh 1 GiB memori
+// sehingga hanya dijalankan bila MAILTM_TEST_SCRYPT_1GIB=1.
+var scryptVectors = []struct {
+	password, salt string
+	N, r, p        int
+	want           string
+}{
+	{"", "", 16, 1, 1, `
+		77 d6 57 62 38 65 7b 20 3b 19 ca 42 c1 8a 04 97
+		f1 6b 48 44 e3 07 4a e8 df df fa 3f ed e2 14 42
+		fc d0 06 9d ed 09 48 f8 32 6a 75 3a 0f c8 1f 17
+		e8 d3 e0 fb 2e 0d 36 28 cf 35 e2 0c 38 d1 89 06`},
+	{"password", "NaCl", 1024, 8, 16, `
+		fd ba be 1c 9d 34 72 00 78 56 e7 19 0d 01 e9 fe
+		7c 6a d7 cb c8 23 78 30 e7 73 76 63 4b 37 31 62
+		2e af 30 d9 2e 22 a3 88 6f f1 09 27 9d 98 30 da
+		c7 27 af b9 4a 83 ee 6d 83 60 cb df a2 cc 06 40`},
+	{"pleaseletmein", "SodiumChloride", 16384, 8, 1, `
+		70 23 bd cb 3a fd 73 48 46 1c 06 cd 81 fd 38 eb
+		fd a8 fb ba 90 4f 8e 3e a9 b5 43 f6 54 5d a1 f2
+		d5 43 29 55 61 3f 0f cf 62 d4 97 05 24 2a 9a f9
+		e6 1e 85 dc 0d 65 1e 40 df cf 01 7b 45 57 58 87`},
+	{"pleaseletmein", "SodiumChloride", 1048576, 8, 1, `
+		21 01 cb 9b 6a 51 1a ae ad db be 09 cf 70 f8 81
+		ec 56 8d 57 4a 2f fd 4d ab e5 ee 98 20 ad aa 47
+		8e 56 fd 8f 4b a5 d0 9f fa 1c 6d 92 7c 40 f4 c3
+		37 30 40 49 e8 a9 52 fb cb f4 5c 6f a7 7a 41 a4`},
+	// parameter yang ditulis penyimpanan (scryptN, scryptR, scryptP) dengan
+	// masukan vektor ketiga; RFC tidak memuatnya, nilainya dari scrypt
+	// OpenSSL (hashlib.scrypt Python)
+	{"pleaseletmein", "SodiumChloride", scryptN, scryptR, scryptP, `
+		f7 2c bc 20 4b dc fc 3f f5 b1 15 d8 50 8a ec 15
+		66 ff 0e f3 f6 58 38 86 01 a3 93 30 78 ef 7a c8
+		19 81 54 d9 cd b1 67 f8 c1 cb f2 2b 25 eb 49 34
+		e2 c8 a9 8d d8 e1 a4 cb f0 c3 1d 2f 96 1a 7f 22`},
+}
+
+func TestScryptRFC7914(t *testing.T) {
+	for _, v := range scryptVectors {
+		if v.N > 1<<16 && os.Getenv("MAILTM_TEST_SCRYPT_1GIB") != "1" {
+			t.Logf("skipping N=%d (set MAILTM_TEST_SCRYPT_1GIB=1)", v.N)
+			continue
+		}
+		want, err := hex.DecodeString(strings.Join(strings.Fields(v.want), ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := scryptKey([]byte(v.password), []byte(v.salt), v.N, v.r, v.p, len(want))
+		if err != nil {
+			t.Fatalf("scrypt(%q, %q, N=%d): %v", v.password, v.salt, v.N, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("scrypt(%q, %q, N=%d, r=%d, p=%d)\n got %x\nwant %x", v.password, v.salt, v.N, v.r, v.p, got, want)
+		}
+	}
+}
+
+func TestScryptBadParams(t *testing.T) {
+	for _, p := range [][3]int{{0, 1, 1}, {1, 1, 1}, {15, 1, 1}, {16, 0, 1}, {16, 1, 0}, {16, 1 << 20, 1 << 10}} {
+		if _, err := scryptKey([]byte("p"), []byte("s"), p[0], p[1], p[2], 32); err == nil {
+			t.Errorf("scrypt N=%d r=%d p=%d: expected error", p[0], p[1], p[2])
+		}
+	}
+}
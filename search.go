@@ -0,0 +1,671 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+// ========================= Pencarian =========================
+
+// Field yang diindeks. Isi teks dan HTML (tanpa tag) diindeks terpisah lalu
+// diambil yang terbaik, agar pesan yang punya keduanya tidak terhitung dua
+// kali.
+const (
+	fieldSubject = iota
+	fieldFrom
+	fieldText
+	fieldHTML
+	numFields
+)
+
+var fieldWeight = [numFields]float64{3, 2, 1, 1}
+
+// parameter BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type searchDoc struct {
+	Account  string // alamat akun
+	Nickname string
+	Key      string // key akun di penyimpanan; kosong bila tidak diketahui
+	Msg      *mailtm.Message
+	At       time.Time
+	lens     [numFields]int
+}
+
+type posting struct {
+	doc   int
+	field int
+	pos   []int
+}
+
+// searchIndex adalah indeks terbalik di memori: term -> dokumen, field dan
+// posisinya. Posisi dipakai untuk pencarian frasa ("...").
+type searchIndex struct {
+	docs  []searchDoc
+	terms map[string][]posting
+	vocab []string // term terurut untuk pencarian awalan; nil = perlu disusun
+	avg   [numFields]float64
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{terms: map[string][]posting{}}
+}
+
+func (ix *searchIndex) Len() int { return len(ix.docs) }
+
+// tokenize memecah teks menjadi term huruf kecil. Term satu karakter dibuang
+// kecuali angka.
+func tokenize(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if utf8.RuneCountInString(w) > 1 || unicode.IsDigit([]rune(w)[0]) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Add mengindeks satu pesan lengkap milik akun address.
+func (ix *searchIndex) Add(address, nickname, key string, m *mailtm.Message) {
+	doc := len(ix.docs)
+	d := searchDoc{Account: address, Nickname: nickname, Key: key, Msg: m}
+	d.At, _ = time.Parse(time.RFC3339, m.CreatedAt)
+	fields := [numFields]string{
+		fieldSubject: m.Subject,
+		fieldFrom:    m.From.Name + " " + m.From.Address,
+		fieldText:    m.Text,
+		fieldHTML:    mailtm.HTMLToText(m.HTML.String()),
+	}
+	for f, s := range fields {
+		toks := tokenize(s)
+		d.lens[f] = len(toks)
+		pos := map[string][]int{}
+		for i, t := range toks {
+			pos[t] = append(pos[t], i)
+		}
+		for t, p := range pos {
+			ix.terms[t] = append(ix.terms[t], posting{doc: doc, field: f, pos: p})
+		}
+	}
+	ix.docs = append(ix.docs, d)
+	ix.vocab = nil
+}
+
+func (ix *searchIndex) prepare() {
+	if ix.vocab != nil {
+		return
+	}
+	ix.vocab = make([]string, 0, len(ix.terms))
+	for t := range ix.terms {
+		ix.vocab = append(ix.vocab, t)
+	}
+	sort.Strings(ix.vocab)
+	var sum [numFields]int
+	for _, d := range ix.docs {
+		for f, n := range d.lens {
+			sum[f] += n
+		}
+	}
+	for f := range sum {
+		ix.avg[f] = max(float64(sum[f])/float64(max(len(ix.docs), 1)), 1)
+	}
+}
+
+// queryTerm adalah satu kata, awalan (kata*) atau frasa dari query.
+type queryTerm struct {
+	words  []string
+	prefix bool // satu kata yang dicocokkan sebagai awalan
+}
+
+// searchQuery adalah hasil parseSearchQuery.
+type searchQuery struct {
+	terms, notTerms     []queryTerm
+	subject, notSubject []queryTerm
+	from, notFrom       []string
+	account, notAccount []string
+	after, before       time.Time
+}
+
+// parseSearchQuery membaca query bergaya mesin pencari surel:
+//
+//	kata "frasa persis" awal* -kecuali
+//	from:stripe subject:"order shipped" account:kerja
+//	after:2026-01-31 before:7d
+//
+// Nilai from: dan account: dicocokkan sebagai potongan teks; before:/after:
+// menerima tanggal (YYYY-MM-DD), RFC3339, atau umur relatif seperti 7d, 2w,
+// 12h. Semua syarat harus terpenuhi; tanda - di depan membaliknya.
+func parseSearchQuery(q string) (searchQuery, error) {
+	var sq searchQuery
+	for _, tok := range splitQuery(q) {
+		neg := false
+		if len(tok) > 1 && strings.HasPrefix(tok, "-") {
+			neg, tok = true, tok[1:]
+		}
+		name, value, ok := strings.Cut(tok, ":")
+		name = strings.ToLower(name)
+		switch {
+		case ok && value != "" && name == "from":
+			if neg {
+				sq.notFrom = append(sq.notFrom, strings.ToLower(unquote(value)))
+			} else {
+				sq.from = append(sq.from, strings.ToLower(unquote(value)))
+			}
+		case ok && value != "" && name == "account":
+			if neg {
+				sq.notAccount = append(sq.notAccount, strings.ToLower(unquote(value)))
+			} else {
+				sq.account = append(sq.account, strings.ToLower(unquote(value)))
+			}
+		case ok && value != "" && name == "subject":
+			if t, ok := makeQueryTerm(value); ok {
+				if neg {
+					sq.notSubject = append(sq.notSubject, t)
+				} else {
+					sq.subject = append(sq.subject, t)
+				}
+			}
+		case ok && value != "" && (name == "before" || name == "after"):
+			t, err := parseSearchTime(unquote(value))
+			if err != nil {
+				return sq, fmt.Errorf("%s: %w", name, err)
+			}
+			if name == "before" {
+				sq.before = t
+			} else {
+				sq.after = t
+			}
+		default:
+			if t, ok := makeQueryTerm(tok); ok {
+				if neg {
+					sq.notTerms = append(sq.notTerms, t)
+				} else {
+					sq.terms = append(sq.terms, t)
+				}
+			}
+		}
+	}
+	return sq, nil
+}
+
+// splitQuery memecah query di spasi, kecuali di dalam tanda kutip.
+func splitQuery(q string) []string {
+	var out []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+func makeQueryTerm(s string) (queryTerm, bool) {
+	phrase := strings.HasPrefix(s, `"`)
+	s = unquote(s)
+	t := queryTerm{prefix: !phrase && strings.HasSuffix(s, "*")}
+	t.words = tokenize(s)
+	if t.prefix && len(t.words) == 0 {
+		// awalan satu huruf, mis. "s*", tetap boleh
+		if w := strings.ToLower(strings.TrimRight(s, "*")); w != "" {
+			t.words = []string{w}
+		}
+	}
+	return t, len(t.words) > 0
+}
+
+// parseSearchTime menerima YYYY-MM-DD (awal hari, waktu lokal), RFC3339, atau
+// umur relatif dari sekarang (30m, 12h, 7d, 2w).
+func parseSearchTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if len(s) > 1 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err == nil && n >= 0 {
+			unit := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+			if unit > 0 {
+				return time.Now().Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD, RFC3339 or an age like 7d)", s)
+}
+
+// match mengembalikan frekuensi term per field untuk setiap dokumen yang
+// mengandung t, hanya di field yang diizinkan fields.
+func (ix *searchIndex) match(t queryTerm, fields []int) map[int]*[numFields]float64 {
+	allowed := [numFields]bool{}
+	for _, f := range fields {
+		allowed[f] = true
+	}
+	out := map[int]*[numFields]float64{}
+	add := func(doc, field int, n float64) {
+		if out[doc] == nil {
+			out[doc] = &[numFields]float64{}
+		}
+		out[doc][field] += n
+	}
+
+	if len(t.words) == 1 {
+		terms := []string{t.words[0]}
+		if t.prefix {
+			terms = ix.expand(t.words[0])
+		}
+		for _, term := range terms {
+			for _, p := range ix.terms[term] {
+				if allowed[p.field] {
+					add(p.doc, p.field, float64(len(p.pos)))
+				}
+			}
+		}
+		return out
+	}
+
+	// frasa: kata ke-i harus ada di posisi awal+i pada field yang sama
+	type key struct{ doc, field int }
+	positions := make([]map[key][]int, len(t.words))
+	for i, w := range t.words {
+		positions[i] = map[key][]int{}
+		for _, p := range ix.terms[w] {
+			positions[i][key{p.doc, p.field}] = p.pos
+		}
+	}
+	for k, starts := range positions[0] {
+		if !allowed[k.field] {
+			continue
+		}
+		n := 0
+		for _, s := range starts {
+			ok := true
+			for i := 1; i < len(t.words) && ok; i++ {
+				_, ok = slices.BinarySearch(positions[i][k], s+i)
+			}
+			if ok {
+				n++
+			}
+		}
+		if n > 0 {
+			add(k.doc, k.field, float64(n))
+		}
+	}
+	return out
+}
+
+// expand mengembalikan semua term berawalan prefix.
+func (ix *searchIndex) expand(prefix string) []string {
+	i := sort.SearchStrings(ix.vocab, prefix)
+	var out []string
+	for ; i < len(ix.vocab) && strings.HasPrefix(ix.vocab[i], prefix); i++ {
+		out = append(out, ix.vocab[i])
+	}
+	return out
+}
+
+// weightedTF menggabungkan frekuensi per field dengan bobot dan normalisasi
+// panjang BM25F; teks dan HTML diambil yang terbesar.
+func (ix *searchIndex) weightedTF(doc int, tf *[numFields]float64) float64 {
+	d := &ix.docs[doc]
+	norm := func(f int) float64 {
+		return tf[f] / (1 - bm25B + bm25B*float64(d.lens[f])/ix.avg[f])
+	}
+	w := fieldWeight[fieldSubject]*norm(fieldSubject) + fieldWeight[fieldFrom]*norm(fieldFrom)
+	return w + max(fieldWeight[fieldText]*norm(fieldText), fieldWeight[fieldHTML]*norm(fieldHTML))
+}
+
+type searchHit struct {
+	Doc   *searchDoc
+	Score float64
+}
+
+var allFields = []int{fieldSubject, fieldFrom, fieldText, fieldHTML}
+
+// Search mengembalikan dokumen yang memenuhi q, skor tertinggi dulu. Tanpa
+// kata pencarian, hasil diurutkan dari yang terbaru.
+func (ix *searchIndex) Search(q searchQuery) []searchHit {
+	ix.prepare()
+	cand := map[int]float64{}
+	for i := range ix.docs {
+		if ix.docs[i].matchFilters(q) {
+			cand[i] = 0
+		}
+	}
+
+	type scored struct {
+		t      queryTerm
+		fields []int
+	}
+	var pos []scored
+	for _, t := range q.terms {
+		pos = append(pos, scored{t, allFields})
+	}
+	for _, t := range q.subject {
+		pos = append(pos, scored{t, []int{fieldSubject}})
+	}
+	n := float64(len(ix.docs))
+	for _, s := range pos {
+		tfs := ix.match(s.t, s.fields)
+		df := float64(len(tfs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for doc := range cand {
+			tf, ok := tfs[doc]
+			if !ok {
+				delete(cand, doc) // semua kata wajib ada
+				continue
+			}
+			w := ix.weightedTF(doc, tf)
+			cand[doc] += idf * w * (bm25K1 + 1) / (w + bm25K1)
+		}
+	}
+	for _, t := range q.notTerms {
+		for doc := range ix.match(t, allFields) {
+			delete(cand, doc)
+		}
+	}
+	for _, t := range q.notSubject {
+		for doc := range ix.match(t, []int{fieldSubject}) {
+			delete(cand, doc)
+		}
+	}
+
+	hits := make([]searchHit, 0, len(cand))
+	for doc, score := range cand {
+		hits = append(hits, searchHit{Doc: &ix.docs[doc], Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if !hits[i].Doc.At.Equal(hits[j].Doc.At) {
+			return hits[i].Doc.At.After(hits[j].Doc.At)
+		}
+		return hits[i].Doc.Msg.ID > hits[j].Doc.Msg.ID
+	})
+	return hits
+}
+
+// matchFilters memeriksa syarat yang bukan kata pencarian.
+func (d *searchDoc) matchFilters(q searchQuery) bool {
+	from := strings.ToLower(d.Msg.From.Name + " " + d.Msg.From.Address)
+	if !q.matchAccount(accountMatchText(d.Account, d.Nickname, d.Key)) {
+		return false
+	}
+	for _, v := range q.from {
+		if !strings.Contains(from, v) {
+			return false
+		}
+	}
+	for _, v := range q.notFrom {
+		if strings.Contains(from, v) {
+			return false
+		}
+	}
+	if !q.after.IsZero() && d.At.Before(q.after) {
+		return false
+	}
+	if !q.before.IsZero() && !d.At.Before(q.before) {
+		return false
+	}
+	return true
+}
+
+// accountMatchText adalah teks yang dicocokkan dengan account:, sama untuk
+// memilih akun yang diambil dan menyaring hasil.
+func accountMatchText(address, nickname, key string) string {
+	return address + " " + nickname + " " + key
+}
+
+// matchAccount memeriksa account: terhadap teks dari accountMatchText.
+func (q searchQuery) matchAccount(s string) bool {
+	s = strings.ToLower(s)
+	for _, v := range q.account {
+		if !strings.Contains(s, v) {
+			return false
+		}
+	}
+	for _, v := range q.notAccount {
+		if strings.Contains(s, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet mengambil potongan isi di sekitar kata pertama yang dicari.
+func (h searchHit) snippet(q searchQuery, width int) string {
+	text := strings.Join(strings.Fields(h.Doc.Msg.PlainText()), " ")
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	at := -1
+	if len(lower) == len(runes) {
+		for _, t := range append(q.terms, q.subject...) {
+			if i := strings.Index(string(lower), t.words[0]); i >= 0 {
+				at = utf8.RuneCountInString(string(lower)[:i])
+				break
+			}
+		}
+	}
+	start := max(at-width/3, 0)
+	end := min(start+width, len(runes))
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+// searchAccountKeys memilih akun tersimpan yang cocok dengan account: di q,
+// untuk diperbarui sebelum mencari.
+func searchAccountKeys(store *Storage, q searchQuery) []string {
+	var keys []string
+	for _, k := range selectAccountKeys(store, nil) {
+		acc, _ := store.Get(k)
+		if q.matchAccount(accountMatchText(acc.Address, acc.Nickname, k)) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// refreshArchive menyalin pesan baru dari akun keys ke arsip agar ikut
+// dicari. Akun yang gagal dilewati; error-nya dikembalikan per akun.
+func refreshArchive(ctx context.Context, ar *Archive, store *Storage, keys []string) []error {
+	var errs []error
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return append(errs, ctx.Err())
+		}
+		acc, _ := store.Get(key)
+		client := newClient(store, sharedHTTP())
+		err := client.LoadAccount(ctx, key)
+		if err == nil {
+			_, err = ar.Sync(ctx, client, archiveOptions{})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", acc.Address, err))
+		}
+	}
+	return errs
+}
+
+// liveMessages adalah pesan satu akun yang diambil dari server untuk satu
+// pencarian saja, tanpa disimpan ke arsip.
+type liveMessages struct {
+	Address  string
+	Nickname string
+	Key      string
+	Messages []mailtm.Message
+}
+
+// searchFetchParallel membatasi isi pesan yang diambil bersamaan; limiter
+// request bersama tetap berlaku di atasnya.
+const searchFetchParallel = 4
+
+// fetchMessages mengambil pesan akun keys dari server ke memori. Daftar pesan
+// disaring dulu dengan syarat q yang bisa diperiksa tanpa isi (account:,
+// from:, before:, after:); hanya kandidat yang diambil isinya. Pesan yang
+// sudah ada di arsip tidak diambil ulang karena sudah ikut terindeks. Akun
+// yang gagal dilewati; error-nya dikembalikan per akun.
+func fetchMessages(ctx context.Context, ar *Archive, store *Storage, keys []string, q searchQuery) ([]liveMessages, []error) {
+	var out []liveMessages
+	var errs []error
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return out, append(errs, ctx.Err())
+		}
+		acc, _ := store.Get(key)
+		client := newClient(store, sharedHTTP())
+		live := liveMessages{Address: acc.Address, Nickname: acc.Nickname, Key: key}
+		err := client.LoadAccount(ctx, key)
+		if err == nil {
+			var ids []string
+			for m, lerr := range client.AllMessages(ctx) {
+				if lerr != nil {
+					err = lerr
+					break
+				}
+				if ar.HasMessage(acc.Address, m.ID) {
+					continue
+				}
+				cand := searchDoc{Account: acc.Address, Nickname: acc.Nickname, Key: key, Msg: &m}
+				cand.At, _ = time.Parse(time.RFC3339, m.CreatedAt)
+				if cand.matchFilters(q) {
+					ids = append(ids, m.ID)
+				}
+			}
+			if err == nil {
+				live.Messages, err = fetchBodies(ctx, client, ids)
+			}
+		}
+		// yang sempat diambil tetap ikut dicari
+		out = append(out, live)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", acc.Address, err))
+		}
+	}
+	return out, errs
+}
+
+// fetchBodies mengambil isi pesan ids, paling banyak searchFetchParallel
+// sekaligus, dengan urutan tetap. Pesan yang sudah dihapus dilewati; pada
+// error pertama sisanya dibatalkan dan yang sudah terambil dikembalikan.
+func fetchBodies(ctx context.Context, client *Client, ids []string) ([]mailtm.Message, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	got := make([]*mailtm.Message, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, searchFetchParallel)
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			// salinan sendiri: pembaruan token mengubah field Client
+			c := *client.Client
+			d, err := c.GetMessage(ctx, id)
+			switch {
+			case errors.Is(err, mailtm.ErrNotFound):
+				// dihapus sejak daftar diambil
+			case err != nil:
+				errs[i] = fmt.Errorf("message %s: %w", id, err)
+				cancel()
+			default:
+				got[i] = d
+			}
+		}()
+	}
+	wg.Wait()
+	var out []mailtm.Message
+	for _, d := range got {
+		if d != nil {
+			out = append(out, *d)
+		}
+	}
+	for _, err := range errs {
+		// error akibat pembatalan sendiri tidak dilaporkan
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// buildSearchIndex mengindeks semua pesan di arsip ditambah pesan live.
+// Key akun diambil dari akun live bila ada, karena key di arsip adalah key
+// saat sinkronisasi terakhir.
+func buildSearchIndex(ar *Archive, live []liveMessages) (*searchIndex, error) {
+	accts, _, err := ar.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]string{}
+	for _, l := range live {
+		keys[strings.ToLower(l.Address)] = l.Key
+	}
+	ix := newSearchIndex()
+	for _, a := range accts {
+		msgs, err := ar.Messages(a.Address)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := keys[strings.ToLower(a.Address)]
+		if !ok {
+			key = a.Key
+		}
+		for i := range msgs {
+			ix.Add(a.Address, a.Nickname, key, &msgs[i])
+		}
+	}
+	for _, l := range live {
+		for i := range l.Messages {
+			ix.Add(l.Address, l.Nickname, l.Key, &l.Messages[i])
+		}
+	}
+	return ix, nil
+}
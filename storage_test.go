@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncryptResealsBackups(t *testing.T) {
+	t.Cleanup(func() { cachedPassphrase = "" })
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	s := NewStorage(file)
+	for _, a := range []string{"a@x.test", "b@x.test", "c@x.test"} {
+		if _, err := s.Add(a, "rahasia-"+a, "id", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups := func() map[string][]byte {
+		out := map[string][]byte{}
+		for i := 1; i <= storeBackups; i++ {
+			if b, err := os.ReadFile(backupName(file, i)); err == nil {
+				out[backupName(file, i)] = b
+			}
+		}
+		return out
+	}
+	// semua backup harus bisa dibuka dengan pass (kosong = JSON biasa)
+	check := func(step, pass string) {
+		t.Helper()
+		bs := backups()
+		if len(bs) == 0 {
+			t.Fatalf("%s: no backups", step)
+		}
+		for name, b := range bs {
+			ef := parseEncrypted(b)
+			if pass == "" {
+				if ef != nil {
+					t.Fatalf("%s: %s is still encrypted", step, name)
+				}
+				continue
+			}
+			if ef == nil || bytes.Contains(b, []byte("rahasia-")) {
+				t.Fatalf("%s: %s is not encrypted", step, name)
+			}
+			if _, _, err := openSealed(ef, pass, nil); err != nil {
+				t.Fatalf("%s: %s: %v", step, name, err)
+			}
+		}
+	}
+
+	if err := s.Encrypt("pp"); err != nil {
+		t.Fatal(err)
+	}
+	check("encrypt", "pp")
+	if err := s.Encrypt("qq"); err != nil {
+		t.Fatal(err)
+	}
+	check("rekey", "qq")
+	if err := s.Decrypt(); err != nil {
+		t.Fatal(err)
+	}
+	check("decrypt", "")
+
+	// backup dari passphrase yang tidak diketahui dihapus, bukan dibiarkan
+	old, _ := newSealer("lama")
+	sealed, _ := old.seal([]byte("{}"))
+	if err := os.WriteFile(backupName(file, 1), sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Encrypt("rr"); err != nil {
+		t.Fatal(err)
+	}
+	check("encrypt again", "rr")
+}
+
+// storageWorker dijalankan di subproses oleh TestStorageConcurrentProcesses:
+// menambah perWorker akun lalu menghapus yang bernomor ganjil.
+const storageWorkerEnv = "MAILTM_TEST_STORAGE_WORKER"
+
+const perWorker = 12
+
+func storageWork(file, worker string) error {
+	s := NewStorage(file)
+	for i := range perWorker {
+		key := fmt.Sprintf("%s-%02d", worker, i)
+		if _, err := s.Add(key+"@x.test", "pw", "id", key); err != nil {
+			return err
+		}
+	}
+	for i := 1; i < perWorker; i += 2 {
+		// instance baru: snapshot lama tidak berisi akun yang dihapus
+		if !NewStorage(file).Remove(fmt.Sprintf("%s-%02d", worker, i)) {
+			return fmt.Errorf("remove %s-%02d failed", worker, i)
+		}
+	}
+	return nil
+}
+
+func wantStorageKeys(workers []string) []string {
+	var want []string
+	for _, w := range workers {
+		for i := 0; i < perWorker; i += 2 {
+			want = append(want, fmt.Sprintf("%s-%02d", w, i))
+		}
+	}
+	slices.Sort(want)
+	return want
+}
+
+func storageKeys(t *testing.T, file string) []string {
+	t.Helper()
+	s := NewStorage(file)
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	keys := slices.Collect(maps.Keys(s.All()))
+	slices.Sort(keys)
+	return keys
+}
+
+func TestStorageWorker(t *testing.T) {
+	file := os.Getenv(storageWorkerEnv)
+	if file == "" {
+		t.Skip("helper for TestStorageConcurrentProcesses")
+	}
+	if err := storageWork(file, os.Getenv(storageWorkerEnv+"_ID")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func TestStorageConcurrentProcesses(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	workers := []string{"p0", "p1", "p2", "p3"}
+	cmds := make([]*exec.Cmd, len(workers))
+	for i, w := range workers {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestStorageWorker$")
+		cmd.Env = append(os.Environ(), storageWorkerEnv+"="+file, storageWorkerEnv+"_ID="+w)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds[i] = cmd
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("worker %s: %v", workers[i], err)
+		}
+	}
+	if got, want := storageKeys(t, file), wantStorageKeys(workers); !slices.Equal(got, want) {
+		t.Fatalf("keys = %v\nwant %v", got, want)
+	}
+}
+
+func TestStorageConcurrentGoroutines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	workers := []string{"g0", "g1", "g2", "g3", "g4", "g5"}
+	var wg sync.WaitGroup
+	errs := make([]error, len(workers))
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = storageWork(file, w)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := storageKeys(t, file), wantStorageKeys(workers); !slices.Equal(got, want) {
+		t.Fatalf("keys = %v\nwant %v", got, want)
+	}
+}
+
+// Remove harus melihat akun yang ditambahkan proses lain setelah Load.
+func TestStorageRemoveAddedElsewhere(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	stale := NewStorage(file)
+	if _, err := NewStorage(file).Add("baru@x.test", "pw", "id", "baru"); err != nil {
+		t.Fatal(err)
+	}
+	if !stale.Remove("baru") {
+		t.Fatal("Remove returned false for an account added by another instance")
+	}
+	if stale.Remove("baru") {
+		t.Fatal("second Remove returned true")
+	}
+	if keys := storageKeys(t, file); len(keys) != 0 {
+		t.Fatalf("keys = %v", keys)
+	}
+}
+
+func TestSetTokenKeepsBackups(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "email_accounts.json")
+	s := NewStorage(file)
+	for _, a := range []string{"a@x.test", "b@x.test"} {
+		if _, err := s.Add(a, "pw", "id", a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bak1, err := os.ReadFile(backupName(file, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 * storeBackups {
+		if err := s.SetToken("a@x.test", fmt.Sprintf("token-%d", i), time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after, _ := os.ReadFile(backupName(file, 1)); !bytes.Equal(after, bak1) {
+		t.Fatal("SetToken rotated the backups")
+	}
+	if _, err := os.Stat(backupName(file, 2)); !os.IsNotExist(err) {
+		t.Fatalf(".bak.2 exists after token updates: %v", err)
+	}
+	if acc, _ := NewStorage(file).Get("a@x.test"); acc.Token != fmt.Sprintf("token-%d", 2*storeBackups-1) {
+		t.Fatalf("token = %q", acc.Token)
+	}
+	// perubahan akun tetap membuat backup
+	if err := s.SetNickname("b@x.test", "kedua"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backupName(file, 2)); err != nil {
+		t.Fatalf("SetNickname did not rotate backups: %v", err)
+	}
+}
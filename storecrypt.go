@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ========================= Penyimpanan terenkripsi =========================
+
+const (
+	encFormat  = "mailtm-encrypted"
+	encVersion = 1
+
+	// parameter scrypt bawaan (~100ms di laptop biasa)
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var errBadPassphrase = errors.New("wrong passphrase or corrupted store")
+
+type kdfParams struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+type encryptedFile struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	KDF        kdfParams `json:"kdf"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+func (k kdfParams) equal(o kdfParams) bool {
+	return k.Name == o.Name && k.N == o.N && k.R == o.R && k.P == o.P && bytes.Equal(k.Salt, o.Salt)
+}
+
+// sealer menyimpan kunci turunan agar scrypt tidak dihitung ulang setiap Save.
+type sealer struct {
+	kdf kdfParams
+	key []byte
+}
+
+func newSealer(passphrase string) (*sealer, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	kdf := kdfParams{Name: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: salt}
+	key, err := scryptKey([]byte(passphrase), salt, kdf.N, kdf.R, kdf.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{kdf: kdf, key: key}, nil
+}
+
+func (s *sealer) seal(plain []byte) ([]byte, error) {
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ef := encryptedFile{
+		Format:     encFormat,
+		Version:    encVersion,
+		KDF:        s.kdf,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain, encAAD()),
+	}
+	return json.MarshalIndent(ef, "", "  ")
+}
+
+// openSealed mendekripsi isi file terenkripsi dan mengembalikan sealer yang
+// bisa dipakai ulang untuk Save berikutnya.
+func openSealed(ef *encryptedFile, passphrase string, known *sealer) ([]byte, *sealer, error) {
+	if ef.Version != encVersion {
+		return nil, nil, fmt.Errorf("unsupported encrypted store version %d", ef.Version)
+	}
+	if ef.KDF.Name != "scrypt" {
+		return nil, nil, fmt.Errorf("unsupported kdf %q", ef.KDF.Name)
+	}
+	var key []byte
+	if known != nil && known.kdf.equal(ef.KDF) {
+		key = known.key
+	} else {
+		var err error
+		key, err = scryptKey([]byte(passphrase), ef.KDF.Salt, ef.KDF.N, ef.KDF.R, ef.KDF.P, 32)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ef.Nonce) != gcm.NonceSize() {
+		return nil, nil, errBadPassphrase
+	}
+	plain, err := gcm.Open(nil, ef.Nonce, ef.Ciphertext, encAAD())
+	if err != nil {
+		return nil, nil, errBadPassphrase
+	}
+	return plain, &sealer{kdf: ef.KDF, key: key}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encAAD() []byte { return []byte(fmt.Sprintf("%s/v%d", encFormat, encVersion)) }
+
+// parseEncrypted mengembalikan nil jika data bukan format terenkripsi.
+func parseEncrypted(data []byte) *encryptedFile {
+	if !bytes.Contains(data, []byte(encFormat)) {
+		return nil
+	}
+	var ef encryptedFile
+	if err := json.Unmarshal(data, &ef); err != nil || ef.Format != encFormat {
+		return nil
+	}
+	return &ef
+}
+
+// ========================= Passphrase =========================
+
+// passphrase yang sudah dimasukkan dipakai ulang selama proses berjalan
+var cachedPassphrase string
+
+func storePassphrase() (string, error) {
+	if cachedPassphrase != "" {
+		return cachedPassphrase, nil
+	}
+	if p := os.Getenv("MAILTM_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	p := readSecret("Passphrase penyimpanan akun: ")
+	if p == "" {
+		return "", errors.New("passphrase is required for the encrypted store (set MAILTM_PASSPHRASE)")
+	}
+	return p, nil
+}
+
+// newPassphrase meminta passphrase baru dua kali, atau memakai env var.
+func newPassphrase(env string) (string, error) {
+	if p := os.Getenv(env); p != "" {
+		return p, nil
+	}
+	p := readSecret("Passphrase baru: ")
+	if p == "" {
+		return "", fmt.Errorf("passphrase must not be empty (or set %s)", env)
+	}
+	if readSecret("Ulangi passphrase baru: ") != p {
+		return "", errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+// readSecret membaca baris dari stdin tanpa menampilkannya bila memungkinkan.
+func readSecret(prompt string) string {
+	fmt.Fprint(os.Stderr, prompt)
+	echoOff := false
+	if runtime.GOOS != "windows" {
+		cmd := exec.Command("stty", "-echo")
+		cmd.Stdin = os.Stdin
+		echoOff = cmd.Run() == nil
+	}
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if echoOff {
+		cmd := exec.Command("stty", "echo")
+		cmd.Stdin = os.Stdin
+		_ = cmd.Run()
+		fmt.Fprintln(os.Stderr)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luzyver/Mail.TM-CLI/mailtm"
+)
+
+// ========================= Watch banyak akun =========================
+
+// limitDoer membatasi jumlah request HTTP yang berjalan bersamaan. Stream SSE
+// hanya memegang slot sampai header diterima, jadi hub tetap bisa dipantau
+// untuk semua akun.
+type limitDoer struct {
+	next mailtm.HTTPDoer
+	sem  chan struct{}
+}
+
+func (d *limitDoer) Do(req *http.Request) (*http.Response, error) {
+	select {
+	case d.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-d.sem }()
+	return d.next.Do(req)
+}
+
+// feedEvent adalah satu kejadian di feed gabungan: pesan baru atau error
+// dari satu akun.
+type feedEvent struct {
+	Key     string
+	Label   string // nickname, atau alamat bila tanpa nickname
+	Address string
+	Message mailtm.Message
+	Err     error
+}
+
+// feedRetryMax adalah jeda terlama sebelum akun yang error dicoba lagi.
+const feedRetryMax = 2 * time.Minute
+
+type watchOptions struct {
+	Interval time.Duration // jeda polling bila hub Mercure tidak dipakai
+	Parallel int           // batas request HTTP bersamaan untuk semua akun
+	Poll     bool          // jangan pakai hub Mercure
+}
+
+// watchAccounts memantau akun-akun keys sekaligus dan memanggil fn (secara
+// berurutan, tidak pernah bersamaan) untuk setiap pesan baru atau error.
+// Error satu akun tidak menghentikan akun lain: akun dicoba lagi dengan
+// backoff, kecuali kredensialnya ditolak. Berhenti saat ctx selesai.
+func watchAccounts(ctx context.Context, store *Storage, keys []string, opt watchOptions, fn func(feedEvent)) error {
+	if len(keys) == 0 {
+		return errors.New("no accounts to watch")
+	}
+	doer := &limitDoer{next: sharedHTTP(), sem: make(chan struct{}, max(opt.Parallel, 1))}
+
+	var mu sync.Mutex
+	send := func(ev feedEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(ev)
+	}
+
+	var wg sync.WaitGroup
+	var failed sync.Map // key -> error terakhir, untuk akun yang menyerah
+	for _, key := range keys {
+		acc, _ := store.Get(key)
+		base := feedEvent{Key: key, Label: nz(acc.Nickname, acc.Address), Address: acc.Address}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := watchOne(ctx, store, doer, key, opt, base, send); err != nil {
+				failed.Store(key, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	// semua goroutine hanya berhenti sendiri bila kredensialnya ditolak
+	var errs []error
+	failed.Range(func(k, v any) bool {
+		errs = append(errs, fmt.Errorf("%s: %w", k, v.(error)))
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// watchOne memantau satu akun sampai ctx selesai. Hanya mengembalikan error
+// bila akun tidak bisa login sama sekali (password salah atau akun dihapus).
+func watchOne(ctx context.Context, store *Storage, doer mailtm.HTTPDoer, key string, opt watchOptions, base feedEvent, send func(feedEvent)) error {
+	client := newClient(store, doer)
+	const firstBackoff = 5 * time.Second
+	backoff := firstBackoff
+	for {
+		started := time.Now()
+		err := client.LoadAccount(ctx, key)
+		if err == nil {
+			if opt.Poll {
+				client.MercureURL = ""
+			}
+			err = client.WatchMessages(ctx, opt.Interval, func(m mailtm.Message) error {
+				ev := base
+				ev.Message = m
+				send(ev)
+				return nil
+			})
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		ev := base
+		ev.Err = err
+		send(ev)
+		if errors.Is(err, mailtm.ErrUnauthorized) {
+			return err
+		}
+		// akun yang sempat sehat lama mulai lagi dari jeda terpendek
+		if time.Since(started) > feedRetryMax {
+			backoff = firstBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, feedRetryMax)
+	}
+}
+
+// selectAccountKeys memilih akun untuk dipantau: semua akun bila tags kosong,
+// atau akun yang punya salah satu tag.
+func selectAccountKeys(store *Storage, tags []string) []string {
+	var keys []string
+	for k, a := range store.All() {
+		if len(tags) == 0 || a.HasTag(tags...) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitTags memecah daftar tag yang dipisah koma.
+func splitTags(s string) []string {
+	var out []string
+	for _, t := range strings.Split(s, ",") {
+		if t = normalizeTag(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}